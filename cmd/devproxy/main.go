@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -22,22 +23,12 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	// Set log level based on configuration
-	var logLevel slog.Level
-	switch cfg.DevProxy.LogLevel {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+	// Handle dry-run rendering, keeping stdout free for the output
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		os.Exit(runRender(cfg, newLogger(cfg, os.Stderr), os.Args[2:]))
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	}))
+	logger := newLogger(cfg, os.Stdout)
 
 	manager, err := proxy.NewManager(cfg, logger)
 	if err != nil {
@@ -59,9 +50,21 @@ func main() {
 	}()
 
 	logger.Info("Starting DevProxy...")
-	logger.Info("📋 Dashboard available at: https://devproxy-dashboard.localhost or http://devproxy-dashboard.localhost")
+	logger.Info("📋 Dashboard available at: " + cfg.DevProxy.URL("https", cfg.Dashboard.Domain) + " or " + cfg.DevProxy.URL("http", cfg.Dashboard.Domain))
 	logger.Info("💡 For HTTPS support: run './trust-cert.sh' then restart your browser")
 
+	go func() {
+		if err := manager.Serve(ctx, cfg.DevProxy.Addr); err != nil {
+			logger.Error("Manager server failed", "error", err)
+		}
+	}()
+
+	go func() {
+		if err := manager.ServeAccessLog(ctx, cfg.DevProxy.AccessLogAddr); err != nil {
+			logger.Error("Access log receiver failed", "error", err)
+		}
+	}()
+
 	if err := manager.Start(ctx); err != nil {
 		logger.Error("Manager failed", "error", err)
 		os.Exit(1)
@@ -69,3 +72,22 @@ func main() {
 
 	logger.Info("DevProxy stopped")
 }
+
+func newLogger(cfg *config.Config, w io.Writer) *slog.Logger {
+	// Set log level based on configuration
+	var logLevel slog.Level
+	switch cfg.DevProxy.LogLevel {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	default:
+		logLevel = slog.LevelInfo
+	}
+
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: logLevel,
+	}))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"devproxy/internal/caddy"
+	"devproxy/internal/config"
+	"devproxy/internal/proxy"
+
+	"github.com/docker/docker/api/types"
+)
+
+// runRender prints the Caddy configuration devproxy would push, without
+// changing anything. It returns the process exit code.
+func runRender(cfg *config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	from := flags.String("from", "", "read containers from a JSON dump of `docker inspect` instead of Docker")
+	diff := flags.Bool("diff", false, "print a diff against Caddy's live configuration")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	manager, err := proxy.NewManager(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create manager", "error", err)
+		return 1
+	}
+
+	ctx := context.Background()
+
+	var rendered *caddy.CaddyConfig
+	if *from != "" {
+		containers, err := loadContainerDump(*from)
+		if err != nil {
+			logger.Error("Failed to load container dump", "file", *from, "error", err)
+			return 1
+		}
+		rendered, err = manager.Render(ctx, containers)
+		if err != nil {
+			logger.Error("Failed to render config", "error", err)
+			return 1
+		}
+	} else {
+		rendered, err = manager.RenderCurrent(ctx)
+		if err != nil {
+			logger.Error("Failed to render config", "error", err)
+			return 1
+		}
+	}
+
+	if !*diff {
+		output, err := json.MarshalIndent(rendered, "", "  ")
+		if err != nil {
+			logger.Error("Failed to serialize config", "error", err)
+			return 1
+		}
+		fmt.Println(string(output))
+		return 0
+	}
+
+	live, err := manager.LiveConfig(ctx)
+	if err != nil {
+		logger.Error("Failed to get live Caddy config", "error", err)
+		return 1
+	}
+
+	output, err := caddy.DiffConfigs(live, rendered, "caddy", "devproxy")
+	if err != nil {
+		logger.Error("Failed to diff configs", "error", err)
+		return 1
+	}
+	if output == "" {
+		logger.Info("Rendered config matches Caddy's live config")
+		return 0
+	}
+
+	fmt.Print(output)
+	return 0
+}
+
+// loadContainerDump reads the output of `docker inspect`, which is either a
+// JSON array of containers or a single container object.
+func loadContainerDump(path string) ([]types.ContainerJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []types.ContainerJSON
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var container types.ContainerJSON
+		if err := json.Unmarshal(trimmed, &container); err != nil {
+			return nil, err
+		}
+		return append(containers, container), nil
+	}
+
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
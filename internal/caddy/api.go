@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -59,7 +60,9 @@ func (c *Client) UpdateConfig(ctx context.Context, config *CaddyConfig) error {
 	return nil
 }
 
-func (c *Client) GetConfig(ctx context.Context) (*CaddyConfig, error) {
+// GetConfig returns Caddy's running configuration as raw JSON, keeping the
+// fields devproxy does not model
+func (c *Client) GetConfig(ctx context.Context) (json.RawMessage, error) {
 	url := fmt.Sprintf("%s/config/", c.baseURL)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -78,12 +81,50 @@ func (c *Client) GetConfig(ctx context.Context) (*CaddyConfig, error) {
 		return nil, fmt.Errorf("caddy API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var config CaddyConfig
-	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
-		return nil, fmt.Errorf("failed to decode config: %w", err)
+	config, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if !json.Valid(config) {
+		return nil, errors.New("failed to decode config: invalid JSON")
+	}
+
+	return config, nil
+}
+
+// UpstreamStatus is Caddy's view of a reverse proxy upstream
+type UpstreamStatus struct {
+	Address     string `json:"address"`
+	NumRequests int    `json:"num_requests"`
+	Fails       int    `json:"fails"`
+}
+
+// GetUpstreams returns the status of every reverse proxy upstream
+func (c *Client) GetUpstreams(ctx context.Context) ([]UpstreamStatus, error) {
+	url := fmt.Sprintf("%s/reverse_proxy/upstreams", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("caddy API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var upstreams []UpstreamStatus
+	if err := json.NewDecoder(resp.Body).Decode(&upstreams); err != nil {
+		return nil, fmt.Errorf("failed to decode upstreams: %w", err)
 	}
 
-	return &config, nil
+	return upstreams, nil
 }
 
 func (c *Client) Health(ctx context.Context) error {
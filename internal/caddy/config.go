@@ -3,12 +3,40 @@ package caddy
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"devproxy/internal/config"
 	"devproxy/internal/docker"
 )
 
 type CaddyConfig struct {
-	Apps CaddyApps `json:"apps"`
+	Logging *CaddyLogging `json:"logging,omitempty"`
+	Apps    CaddyApps     `json:"apps"`
+}
+
+type CaddyLogging struct {
+	Logs map[string]CaddyLog `json:"logs"`
+}
+
+type CaddyLog struct {
+	Writer  *CaddyLogWriter  `json:"writer,omitempty"`
+	Encoder *CaddyLogEncoder `json:"encoder,omitempty"`
+	Include []string         `json:"include,omitempty"`
+	Exclude []string         `json:"exclude,omitempty"`
+}
+
+type CaddyLogWriter struct {
+	Output    string `json:"output"`
+	Address   string `json:"address,omitempty"`
+	SoftStart bool   `json:"soft_start,omitempty"`
+}
+
+type CaddyLogEncoder struct {
+	Format string `json:"format"`
 }
 
 type CaddyApps struct {
@@ -17,7 +45,9 @@ type CaddyApps struct {
 }
 
 type CaddyHTTP struct {
-	Servers map[string]CaddyServer `json:"servers"`
+	HTTPPort  int                    `json:"http_port,omitempty"`
+	HTTPSPort int                    `json:"https_port,omitempty"`
+	Servers   map[string]CaddyServer `json:"servers"`
 }
 
 type CaddyTLS struct {
@@ -39,54 +69,201 @@ type CaddyTLSInternalIssuer struct {
 }
 
 type CaddyServer struct {
-	Listen []string     `json:"listen"`
+	Listen            []string             `json:"listen"`
+	Routes            []CaddyRoute         `json:"routes"`
+	AutomaticHTTPS    *CaddyAutomaticHTTPS `json:"automatic_https,omitempty"`
+	Protocols         []string             `json:"protocols,omitempty"`
+	ReadTimeout       string               `json:"read_timeout,omitempty"`
+	ReadHeaderTimeout string               `json:"read_header_timeout,omitempty"`
+	WriteTimeout      string               `json:"write_timeout,omitempty"`
+	IdleTimeout       string               `json:"idle_timeout,omitempty"`
+	Errors            *CaddyServerErrors   `json:"errors,omitempty"`
+	Logs              *CaddyServerLogs     `json:"logs,omitempty"`
+}
+
+type CaddyServerLogs struct {
+	DefaultLoggerName string `json:"default_logger_name,omitempty"`
+}
+
+type CaddyServerErrors struct {
 	Routes []CaddyRoute `json:"routes"`
 }
 
+type CaddyAutomaticHTTPS struct {
+	DisableRedirects bool `json:"disable_redirects,omitempty"`
+}
+
 type CaddyRoute struct {
-	Match    []CaddyMatch   `json:"match"`
+	Match    []CaddyMatch   `json:"match,omitempty"`
 	Handle   []CaddyHandler `json:"handle"`
 	Terminal bool           `json:"terminal,omitempty"`
 }
 
 type CaddyMatch struct {
-	Host []string `json:"host"`
+	Host     []string       `json:"host"`
+	Path     []string       `json:"path,omitempty"`
+	RemoteIP *CaddyRemoteIP `json:"remote_ip,omitempty"`
+
+	// CEL expression over placeholders, such as {http.error.status_code}
+	Expression string `json:"expression,omitempty"`
+}
+
+type CaddyRemoteIP struct {
+	Ranges []string `json:"ranges"`
 }
 
 type CaddyHandler struct {
 	Handler   string          `json:"handler"`
 	Upstreams []CaddyUpstream `json:"upstreams,omitempty"`
 	Headers   *CaddyHeaders   `json:"headers,omitempty"`
+	Transport *CaddyTransport `json:"transport,omitempty"`
+
+	Rewrite        *CaddyRewrite         `json:"rewrite,omitempty"`
+	HandleResponse []CaddyHandleResponse `json:"handle_response,omitempty"`
+	FlushInterval  string                `json:"flush_interval,omitempty"`
+	LoadBalancing  *CaddyLoadBalancing   `json:"load_balancing,omitempty"`
+	HealthChecks   *CaddyHealthChecks    `json:"health_checks,omitempty"`
+
+	// request_body handler
+	MaxSize int64 `json:"max_size,omitempty"`
+
+	// headers handler
+	Request  *CaddyHeadersOps `json:"request,omitempty"`
+	Response *CaddyHeadersOps `json:"response,omitempty"`
+
+	// authentication handler
+	Providers *CaddyAuthProviders `json:"providers,omitempty"`
+
+	// static_response handler
+	StatusCode string `json:"status_code,omitempty"`
+	Body       string `json:"body,omitempty"`
 }
 
 type CaddyUpstream struct {
 	Dial string `json:"dial"`
 }
 
+type CaddyLoadBalancing struct {
+	TryDuration string `json:"try_duration,omitempty"`
+	TryInterval string `json:"try_interval,omitempty"`
+}
+
+type CaddyHealthChecks struct {
+	Active  *CaddyActiveHealthCheck  `json:"active,omitempty"`
+	Passive *CaddyPassiveHealthCheck `json:"passive,omitempty"`
+}
+
+type CaddyActiveHealthCheck struct {
+	URI          string `json:"uri,omitempty"`
+	Port         int    `json:"port,omitempty"`
+	Interval     string `json:"interval,omitempty"`
+	Timeout      string `json:"timeout,omitempty"`
+	ExpectStatus int    `json:"expect_status,omitempty"`
+}
+
+type CaddyPassiveHealthCheck struct {
+	FailDuration string `json:"fail_duration,omitempty"`
+	MaxFails     int    `json:"max_fails,omitempty"`
+}
+
+type CaddyRewrite struct {
+	Method string `json:"method,omitempty"`
+	URI    string `json:"uri,omitempty"`
+}
+
+type CaddyHandleResponse struct {
+	Match  *CaddyResponseMatch `json:"match,omitempty"`
+	Routes []CaddyRoute        `json:"routes,omitempty"`
+}
+
+type CaddyResponseMatch struct {
+	StatusCode []int `json:"status_code,omitempty"`
+}
+
+type CaddyAuthProviders struct {
+	HTTPBasic *CaddyHTTPBasic `json:"http_basic,omitempty"`
+}
+
+type CaddyHTTPBasic struct {
+	Accounts []CaddyAccount `json:"accounts"`
+	Hash     CaddyHash      `json:"hash"`
+	Realm    string         `json:"realm,omitempty"`
+}
+
+type CaddyAccount struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type CaddyHash struct {
+	Algorithm string `json:"algorithm"`
+}
+
+type CaddyTransport struct {
+	Protocol              string             `json:"protocol"`
+	Versions              []string           `json:"versions,omitempty"`
+	TLS                   *CaddyTransportTLS `json:"tls,omitempty"`
+	DialTimeout           string             `json:"dial_timeout,omitempty"`
+	ResponseHeaderTimeout string             `json:"response_header_timeout,omitempty"`
+	ReadTimeout           string             `json:"read_timeout,omitempty"`
+	WriteTimeout          string             `json:"write_timeout,omitempty"`
+}
+
+type CaddyTransportTLS struct {
+	RootCAPEMFiles     []string `json:"root_ca_pem_files,omitempty"`
+	ServerName         string   `json:"server_name,omitempty"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify,omitempty"`
+}
+
 type CaddyHeaders struct {
-	Request *CaddyHeadersOps `json:"request,omitempty"`
+	Request  *CaddyHeadersOps `json:"request,omitempty"`
+	Response *CaddyHeadersOps `json:"response,omitempty"`
 }
 
 type CaddyHeadersOps struct {
-	Set map[string][]string `json:"set,omitempty"`
+	Set    map[string][]string `json:"set,omitempty"`
+	Add    map[string][]string `json:"add,omitempty"`
+	Delete []string            `json:"delete,omitempty"`
 }
 
-type ConfigGenerator struct{}
+type ConfigGenerator struct {
+	config *config.Config
+}
 
-func NewConfigGenerator() *ConfigGenerator {
-	return &ConfigGenerator{}
+func NewConfigGenerator(cfg *config.Config) *ConfigGenerator {
+	return &ConfigGenerator{
+		config: cfg,
+	}
 }
 
 func (g *ConfigGenerator) GenerateConfig(targets []docker.ProxyTarget) (*CaddyConfig, error) {
+	targets, err := g.applyGlobalBasicAuth(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err = g.applyGlobalAllow(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	// Templates are reloaded on every update so edits apply without a restart
+	pages, err := LoadPages(g.config.DevProxy.TemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := g.generateServers(targets, pages)
+	if err != nil {
+		return nil, err
+	}
+
 	config := &CaddyConfig{
 		Apps: CaddyApps{
 			HTTP: CaddyHTTP{
-				Servers: map[string]CaddyServer{
-					"devproxy": {
-						Listen: []string{":80", ":443"},
-						Routes: g.generateRoutes(targets),
-					},
-				},
+				HTTPPort:  g.config.DevProxy.HTTPPort,
+				HTTPSPort: g.config.DevProxy.HTTPSPort,
+				Servers:   servers,
 			},
 			TLS: CaddyTLS{
 				Automation: CaddyTLSAutomation{
@@ -106,56 +283,727 @@ func (g *ConfigGenerator) GenerateConfig(targets []docker.ProxyTarget) (*CaddyCo
 		},
 	}
 
+	if g.needsAccessLog(targets) {
+		config.Logging = g.generateAccessLogging()
+		for name, server := range config.Apps.HTTP.Servers {
+			server.Logs = &CaddyServerLogs{
+				DefaultLoggerName: AccessLogName,
+			}
+			config.Apps.HTTP.Servers[name] = server
+		}
+	}
+
 	return config, nil
 }
 
-func (g *ConfigGenerator) generateRoutes(targets []docker.ProxyTarget) []CaddyRoute {
-	var routes []CaddyRoute
+// AccessLogName is the logger Caddy sends access logs to when the manager
+// tracks request activity
+const AccessLogName = "devproxy_access"
+
+// dualStackTryDuration is how long requests to dual-stack upstreams are
+// retried when the picked address cannot be reached
+const dualStackTryDuration = 2 * time.Second
+
+// countOnlyMaxFails keeps passive health checks from ever taking an
+// upstream out of rotation, so they only count failures
+const countOnlyMaxFails = 1 << 30
 
-	// Group targets by domain
-	domainTargets := make(map[string][]docker.ProxyTarget)
+// needsAccessLog reports whether a target stops when idle, which the manager
+// detects from access logs
+func (g *ConfigGenerator) needsAccessLog(targets []docker.ProxyTarget) bool {
 	for _, target := range targets {
-		domainTargets[target.Domain] = append(domainTargets[target.Domain], target)
+		if target.IdleTimeout > 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	// Create route for each domain
-	for domain, targets := range domainTargets {
-		// Use the first target (in case of multiple targets for same domain)
-		target := targets[0]
+// generateAccessLogging streams access logs as JSON lines to the manager,
+// keeping them out of Caddy's own output
+func (g *ConfigGenerator) generateAccessLogging() *CaddyLogging {
+	accessLogger := "http.log.access." + AccessLogName
 
-		route := CaddyRoute{
-			Match: []CaddyMatch{
-				{
-					Host: []string{domain},
+	return &CaddyLogging{
+		Logs: map[string]CaddyLog{
+			"default": {
+				Exclude: []string{accessLogger},
+			},
+			AccessLogName: {
+				Writer: &CaddyLogWriter{
+					Output:  "net",
+					Address: g.config.DevProxy.AccessLogDial,
+					// Do not fail the config while the manager is unreachable
+					SoftStart: true,
 				},
+				Encoder: &CaddyLogEncoder{
+					Format: "json",
+				},
+				Include: []string{accessLogger},
 			},
+		},
+	}
+}
+
+// generateServers splits routes between an HTTPS and a plain HTTP server
+// according to each target's TLS mode.
+func (g *ConfigGenerator) generateServers(targets []docker.ProxyTarget, pages *Pages) (map[string]CaddyServer, error) {
+	var httpsRoutes, httpRoutes, errorRoutes []CaddyRoute
+
+	// One route per domain and path, most specific first so Caddy tries it
+	// before broader matches. Conflicting claims are dropped here; callers
+	// report them through docker.ResolveConflicts.
+	winners, _ := docker.ResolveConflicts(targets)
+
+	for _, target := range winners {
+		// Stopped containers keep their route, answering with a page
+		// instead of proxying
+		generateRoute := g.generateProxyRoute
+		if target.Stopped && target.Autostart && target.Crash == nil {
+			generateRoute = g.generateAutostartRoute
+		} else if target.Stopped {
+			stoppedRoute, err := g.generateStoppedRoute(target, pages)
+			if err != nil {
+				return nil, err
+			}
+			generateRoute = func(docker.ProxyTarget) CaddyRoute {
+				return stoppedRoute
+			}
+		}
+
+		switch target.TLSMode {
+		case docker.TLSModeHTTPSOnly:
+			httpsRoutes = append(httpsRoutes, g.restrict(target, generateRoute(target))...)
+		case docker.TLSModeHTTPOnly:
+			httpRoutes = append(httpRoutes, g.restrict(target, generateRoute(target))...)
+		case docker.TLSModeRedirect:
+			httpsRoutes = append(httpsRoutes, g.restrict(target, generateRoute(target))...)
+			httpRoutes = append(httpRoutes, g.restrict(target, g.generateRedirectRoute(target))...)
+		default:
+			httpsRoutes = append(httpsRoutes, g.restrict(target, generateRoute(target))...)
+			httpRoutes = append(httpRoutes, g.restrict(target, generateRoute(target))...)
+		}
+
+		errorRoute, err := g.generateErrorRoute(target, pages)
+		if err != nil {
+			return nil, err
+		}
+		errorRoutes = append(errorRoutes, errorRoute)
+	}
+
+	// Unknown domains get an explanation instead of an empty response
+	notFoundRoute, err := g.generateNotFoundRoute(pages)
+	if err != nil {
+		return nil, err
+	}
+	httpsRoutes = append(httpsRoutes, notFoundRoute)
+	httpRoutes = append(httpRoutes, notFoundRoute)
+
+	httpsServer := g.generateServer(g.config.DevProxy.HTTPSPort, httpsRoutes)
+	// Redirects are emitted explicitly for routes that ask for them
+	httpsServer.AutomaticHTTPS = &CaddyAutomaticHTTPS{
+		DisableRedirects: true,
+	}
+
+	httpServer := g.generateServer(g.config.DevProxy.HTTPPort, httpRoutes)
+	// HTTP/3 requires TLS
+	httpServer.Protocols = slices.DeleteFunc(slices.Clone(httpServer.Protocols), func(protocol string) bool {
+		return protocol == "h3"
+	})
+
+	httpsServer.Errors = &CaddyServerErrors{Routes: errorRoutes}
+	httpServer.Errors = &CaddyServerErrors{Routes: errorRoutes}
+
+	return map[string]CaddyServer{
+		"devproxy_https": httpsServer,
+		"devproxy_http":  httpServer,
+	}, nil
+}
+
+// upstreamErrorExpression matches the errors reverse_proxy raises when it
+// cannot get a response from the upstream
+const upstreamErrorExpression = "{http.error.status_code} in [502, 503, 504]"
+
+// generateErrorRoute serves a page describing the target when Caddy fails to
+// proxy a request to it
+func (g *ConfigGenerator) generateErrorRoute(target docker.ProxyTarget, pages *Pages) (CaddyRoute, error) {
+	body, err := pages.Render(pageError, PageData{
+		Domain:         target.Domain,
+		ContainerName:  target.ContainerName,
+		ContainerState: target.ContainerState,
+		Upstream:       target.Dial(),
+		StatusCode:     "{http.error.status_code}",
+		DashboardURL:   g.config.DevProxy.URL("https", g.config.Dashboard.Domain),
+	})
+	if err != nil {
+		return CaddyRoute{}, err
+	}
+
+	// Only upstream failures get the page; errors of the route's own
+	// handlers, such as 401 from basic auth or 413 from body limits, keep
+	// their status and body
+	match := g.generateMatch(target)
+	for i := range match {
+		match[i].Expression = upstreamErrorExpression
+	}
+
+	return CaddyRoute{
+		Match:    match,
+		Handle:   g.generatePageHandlers("{http.error.status_code}", body),
+		Terminal: true,
+	}, nil
+}
+
+// generateStoppedRoute serves a page explaining that the target's container
+// is stopped and how to start it
+func (g *ConfigGenerator) generateStoppedRoute(target docker.ProxyTarget, pages *Pages) (CaddyRoute, error) {
+	body, err := pages.Render(pageStopped, PageData{
+		Domain:         target.Domain,
+		ContainerName:  target.ContainerName,
+		ContainerState: target.ContainerState,
+		StatusCode:     "503",
+		DashboardURL:   g.config.DevProxy.URL("https", g.config.Dashboard.Domain),
+		StoppedAt:      target.StoppedAt,
+		ExitCode:       target.ExitCode,
+		StartCommand:   "docker start " + target.ContainerName,
+		Crash:          escapeCrashReport(target.Crash),
+	})
+	if err != nil {
+		return CaddyRoute{}, err
+	}
+
+	return CaddyRoute{
+		Match:    g.generateMatch(target),
+		Handle:   g.generatePageHandlers("503", body),
+		Terminal: true,
+	}, nil
+}
+
+// Requests for stopped containers labelled devproxy.autostart are proxied to
+// the manager, rewritten to AutostartPath with the container ID in
+// AutostartHeader
+const (
+	AutostartPath   = "/autostart"
+	AutostartHeader = "X-DevProxy-Autostart"
+)
+
+// generateAutostartRoute hands requests for a stopped container to the
+// manager, which starts it and serves a page until it is ready. Crashed
+// containers are not restarted this way.
+func (g *ConfigGenerator) generateAutostartRoute(target docker.ProxyTarget) CaddyRoute {
+	headers := g.generateHeaders(target)
+	headers.Request.Set[AutostartHeader] = []string{target.ContainerID}
+
+	proxy := CaddyHandler{
+		Handler: "reverse_proxy",
+		Upstreams: []CaddyUpstream{
+			{
+				Dial: g.config.DevProxy.ManagerDial,
+			},
+		},
+		Headers: headers,
+		Rewrite: &CaddyRewrite{
+			URI: AutostartPath,
+		},
+	}
+
+	return CaddyRoute{
+		Match:    g.generateMatch(target),
+		Handle:   append(g.generateAuthHandlers(target), proxy),
+		Terminal: true,
+	}
+}
+
+// placeholderEscaper keeps Caddy from expanding placeholders, such as
+// {env.*}, found in text that does not come from the configuration
+var placeholderEscaper = strings.NewReplacer("{", `\{`, "}", `\}`)
+
+// escapeCrashReport returns a copy of report safe to embed in a Caddy response
+// body, since container output is arbitrary
+func escapeCrashReport(report *docker.CrashReport) *docker.CrashReport {
+	if report == nil {
+		return nil
+	}
+	escaped := *report
+	escaped.Error = placeholderEscaper.Replace(report.Error)
+	escaped.Logs = placeholderEscaper.Replace(report.Logs)
+	return &escaped
+}
+
+func (g *ConfigGenerator) generateNotFoundRoute(pages *Pages) (CaddyRoute, error) {
+	body, err := pages.Render(pageNotFound, PageData{
+		Domain:       "{http.request.host}",
+		StatusCode:   "404",
+		DashboardURL: g.config.DevProxy.URL("https", g.config.Dashboard.Domain),
+	})
+	if err != nil {
+		return CaddyRoute{}, err
+	}
+
+	return CaddyRoute{
+		Handle:   g.generatePageHandlers("404", body),
+		Terminal: true,
+	}, nil
+}
+
+// generatePageHandlers serves an HTML page with the given status code
+func (g *ConfigGenerator) generatePageHandlers(statusCode, body string) []CaddyHandler {
+	return []CaddyHandler{
+		{
+			Handler: "headers",
+			Response: &CaddyHeadersOps{
+				Set: map[string][]string{
+					"Content-Type": {"text/html; charset=utf-8"},
+				},
+			},
+		},
+		{
+			Handler:    "static_response",
+			StatusCode: statusCode,
+			Body:       body,
+		},
+	}
+}
+
+// generateServer applies the configured listen address and server settings
+func (g *ConfigGenerator) generateServer(port int, routes []CaddyRoute) CaddyServer {
+	cfg := g.config.DevProxy
+
+	return CaddyServer{
+		Listen:            []string{net.JoinHostPort(cfg.BindAddr, strconv.Itoa(port))},
+		Routes:            routes,
+		Protocols:         cfg.Protocols,
+		ReadTimeout:       formatDuration(cfg.ReadTimeout),
+		ReadHeaderTimeout: formatDuration(cfg.ReadHeaderTimeout),
+		WriteTimeout:      formatDuration(cfg.WriteTimeout),
+		IdleTimeout:       formatDuration(cfg.IdleTimeout),
+	}
+}
+
+// formatDuration renders a duration for Caddy, leaving zero values unset so
+// Caddy's defaults apply
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// applyGlobalBasicAuth protects targets without their own credentials with
+// the globally configured users, if any.
+func (g *ConfigGenerator) applyGlobalBasicAuth(targets []docker.ProxyTarget) ([]docker.ProxyTarget, error) {
+	var users []docker.BasicAuthUser
+
+	if g.config.DevProxy.BasicAuthUsers != "" {
+		inlineUsers, err := docker.ParseBasicAuthUsers(g.config.DevProxy.BasicAuthUsers)
+		if err != nil {
+			return nil, fmt.Errorf("invalid global basic auth users: %w", err)
+		}
+		users = append(users, inlineUsers...)
+	}
+
+	if g.config.DevProxy.BasicAuthUsersFile != "" {
+		fileUsers, err := docker.LoadBasicAuthFile(g.config.DevProxy.BasicAuthUsersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load global basic auth users file: %w", err)
+		}
+		users = append(users, fileUsers...)
+	}
+
+	if len(users) == 0 {
+		return targets, nil
+	}
+
+	protected := make([]docker.ProxyTarget, len(targets))
+	for i, target := range targets {
+		if target.BasicAuth == nil {
+			target.BasicAuth = &docker.BasicAuth{Users: users}
+		}
+		protected[i] = target
+	}
+
+	return protected, nil
+}
+
+// applyGlobalAllow restricts targets without their own allow list to the
+// globally configured client ranges, if any.
+func (g *ConfigGenerator) applyGlobalAllow(targets []docker.ProxyTarget) ([]docker.ProxyTarget, error) {
+	if g.config.DevProxy.Allow == "" {
+		return targets, nil
+	}
+
+	ranges, err := docker.ParseAllowList(g.config.DevProxy.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid global allow list: %w", err)
+	}
+
+	restricted := make([]docker.ProxyTarget, len(targets))
+	for i, target := range targets {
+		if target.Allow == nil {
+			target.Allow = ranges
+		}
+		restricted[i] = target
+	}
+
+	return restricted, nil
+}
+
+// restrict limits a route to the target's allowed client ranges, followed by
+// a fallback route answering 403 to everyone else.
+func (g *ConfigGenerator) restrict(target docker.ProxyTarget, route CaddyRoute) []CaddyRoute {
+	if target.Allow == nil {
+		return []CaddyRoute{route}
+	}
+
+	forbidden := CaddyRoute{
+		Match: g.generateMatch(target),
+		Handle: []CaddyHandler{
+			{
+				Handler:    "static_response",
+				StatusCode: "403",
+				Body:       "Access to this route is not allowed from your address.",
+			},
+		},
+		Terminal: true,
+	}
+
+	// Nobody is allowed, only the fallback remains
+	if len(target.Allow) == 0 {
+		return []CaddyRoute{forbidden}
+	}
+
+	for i := range route.Match {
+		route.Match[i].RemoteIP = &CaddyRemoteIP{
+			Ranges: target.Allow,
+		}
+	}
+
+	return []CaddyRoute{route, forbidden}
+}
+
+func (g *ConfigGenerator) generateMatch(target docker.ProxyTarget) []CaddyMatch {
+	match := CaddyMatch{
+		Host: []string{target.Domain},
+	}
+	if target.Path != "" {
+		match.Path = []string{target.Path, target.Path + "/*"}
+	}
+	return []CaddyMatch{match}
+}
+
+// generateUpstreams lists the addresses of the target, both families of
+// dual-stack containers
+func (g *ConfigGenerator) generateUpstreams(target docker.ProxyTarget) []CaddyUpstream {
+	var upstreams []CaddyUpstream
+	for _, dial := range target.Dials() {
+		upstreams = append(upstreams, CaddyUpstream{Dial: dial})
+	}
+	return upstreams
+}
+
+func (g *ConfigGenerator) generateProxyRoute(target docker.ProxyTarget) CaddyRoute {
+	handlers := g.generateAuthHandlers(target)
+
+	if target.MaxBodySize > 0 {
+		handlers = append([]CaddyHandler{{Handler: "request_body", MaxSize: target.MaxBodySize}}, handlers...)
+	}
+
+	proxy := CaddyHandler{
+		Handler:       "reverse_proxy",
+		Upstreams:     g.generateUpstreams(target),
+		Headers:       g.generateHeaders(target),
+		Transport:     g.generateTransport(target),
+		FlushInterval: formatDuration(target.FlushInterval),
+	}
+
+	if target.HealthCheck != nil {
+		proxy.HealthChecks = &CaddyHealthChecks{
+			Active: &CaddyActiveHealthCheck{
+				URI:          target.HealthCheck.Path,
+				Port:         target.HealthCheck.Port,
+				Interval:     formatDuration(target.HealthCheck.Interval),
+				Timeout:      formatDuration(target.HealthCheck.Timeout),
+				ExpectStatus: target.HealthCheck.ExpectStatus,
+			},
+			Passive: g.generatePassiveHealthCheck(),
+		}
+	} else if passive := g.generatePassiveHealthCheck(); passive != nil {
+		proxy.HealthChecks = &CaddyHealthChecks{Passive: passive}
+	}
+
+	// Retrying lets requests wait out a container restart instead of failing
+	if target.TryDuration > 0 {
+		proxy.LoadBalancing = &CaddyLoadBalancing{
+			TryDuration: formatDuration(target.TryDuration),
+			TryInterval: formatDuration(target.TryInterval),
+		}
+	} else if target.DualStackIP != "" {
+		// Dual-stack upstreams are picked at random, so a family Caddy
+		// cannot reach is retried on the other one
+		proxy.LoadBalancing = &CaddyLoadBalancing{
+			TryDuration: formatDuration(dualStackTryDuration),
+		}
+	}
+
+	return CaddyRoute{
+		Match:    g.generateMatch(target),
+		Handle:   append(handlers, proxy),
+		Terminal: true,
+	}
+}
+
+// generatePassiveHealthCheck counts upstream failures so their status can be
+// reported through Caddy's admin API. Upstreams are only taken out of
+// rotation when DEVPROXY_PASSIVE_MAX_FAILS opts in; otherwise the limit is
+// out of reach and the check only counts.
+func (g *ConfigGenerator) generatePassiveHealthCheck() *CaddyPassiveHealthCheck {
+	cfg := g.config.DevProxy
+	if cfg.PassiveFailDuration <= 0 {
+		return nil
+	}
+
+	maxFails := cfg.PassiveMaxFails
+	if maxFails <= 0 {
+		maxFails = countOnlyMaxFails
+	}
+
+	return &CaddyPassiveHealthCheck{
+		FailDuration: formatDuration(cfg.PassiveFailDuration),
+		MaxFails:     maxFails,
+	}
+}
+
+// generateAuthHandlers returns the handlers that must run before the request
+// is proxied. A target whose credentials failed to load is locked entirely.
+func (g *ConfigGenerator) generateAuthHandlers(target docker.ProxyTarget) []CaddyHandler {
+	var handlers []CaddyHandler
+
+	if target.BasicAuth != nil {
+		if len(target.BasicAuth.Users) == 0 {
+			return []CaddyHandler{g.generateLockedHandler("Authentication is misconfigured for this route, check the DevProxy logs.")}
+		}
+
+		var accounts []CaddyAccount
+		for _, user := range target.BasicAuth.Users {
+			accounts = append(accounts, CaddyAccount{
+				Username: user.Username,
+				Password: user.Password,
+			})
+		}
+
+		handlers = append(handlers, CaddyHandler{
+			Handler: "authentication",
+			Providers: &CaddyAuthProviders{
+				HTTPBasic: &CaddyHTTPBasic{
+					Accounts: accounts,
+					Hash: CaddyHash{
+						Algorithm: "bcrypt",
+					},
+					Realm: "devproxy",
+				},
+			},
+		})
+	}
+
+	if target.ForwardAuth != nil {
+		if target.ForwardAuth.Dial == "" {
+			return []CaddyHandler{g.generateLockedHandler("The authentication service for this route is not available, check the DevProxy logs.")}
+		}
+		handlers = append(handlers, g.generateForwardAuthHandlers(target.ForwardAuth)...)
+	}
+
+	return handlers
+}
+
+// generateForwardAuthHandlers mirrors Caddy's forward_auth directive: the
+// request is sent to the auth service first; on 2xx its headers are copied
+// onto the request and processing continues, otherwise the auth service's
+// response is returned to the client.
+func (g *ConfigGenerator) generateForwardAuthHandlers(auth *docker.ForwardAuth) []CaddyHandler {
+	var handlers []CaddyHandler
+
+	// Clients must not be able to forge the headers the auth service sets
+	if len(auth.CopyHeaders) > 0 {
+		handlers = append(handlers, CaddyHandler{
+			Handler: "headers",
+			Request: &CaddyHeadersOps{
+				Delete: auth.CopyHeaders,
+			},
+		})
+	}
+
+	successRoutes := []CaddyRoute{
+		{
+			Handle: []CaddyHandler{{Handler: "vars"}},
+		},
+	}
+	if len(auth.CopyHeaders) > 0 {
+		copied := make(map[string][]string)
+		for _, name := range auth.CopyHeaders {
+			copied[name] = []string{fmt.Sprintf("{http.reverse_proxy.header.%s}", name)}
+		}
+		successRoutes = append(successRoutes, CaddyRoute{
 			Handle: []CaddyHandler{
 				{
-					Handler: "reverse_proxy",
-					Upstreams: []CaddyUpstream{
-						{
-							Dial: fmt.Sprintf("%s:%d", target.ContainerIP, target.Port),
-						},
-					},
-					Headers: &CaddyHeaders{
-						Request: &CaddyHeadersOps{
-							Set: map[string][]string{
-								"Host":              {domain},
-								"X-Forwarded-For":   {"{http.request.remote_host}"},
-								"X-Forwarded-Proto": {"https"},
-								"X-Real-IP":         {"{http.request.remote_host}"},
-							},
-						},
+					Handler: "headers",
+					Request: &CaddyHeadersOps{
+						Set: copied,
 					},
 				},
 			},
-			Terminal: true,
+		})
+	}
+
+	handler := CaddyHandler{
+		Handler: "reverse_proxy",
+		Upstreams: []CaddyUpstream{
+			{
+				Dial: auth.Dial,
+			},
+		},
+		Rewrite: &CaddyRewrite{
+			Method: "GET",
+			URI:    auth.URI,
+		},
+		Headers: &CaddyHeaders{
+			Request: &CaddyHeadersOps{
+				Set: map[string][]string{
+					"X-Forwarded-Method": {"{http.request.method}"},
+					"X-Forwarded-Uri":    {"{http.request.uri}"},
+					"X-Forwarded-Host":   {"{http.request.host}"},
+					"X-Forwarded-Proto":  {"{http.request.scheme}"},
+				},
+			},
+		},
+		HandleResponse: []CaddyHandleResponse{
+			{
+				Match: &CaddyResponseMatch{
+					StatusCode: []int{2},
+				},
+				Routes: successRoutes,
+			},
+		},
+	}
+	if auth.TLS {
+		handler.Transport = &CaddyTransport{
+			Protocol: "http",
+			TLS:      &CaddyTransportTLS{},
+		}
+	}
+
+	return append(handlers, handler)
+}
+
+// generateLockedHandler rejects every request to a route whose protection
+// could not be set up
+func (g *ConfigGenerator) generateLockedHandler(message string) CaddyHandler {
+	return CaddyHandler{
+		Handler:    "static_response",
+		StatusCode: "503",
+		Body:       message,
+	}
+}
+
+// generateHeaders combines the default proxy headers with the target's
+// header labels. Labels that set or delete a default header override it.
+func (g *ConfigGenerator) generateHeaders(target docker.ProxyTarget) *CaddyHeaders {
+	request := &CaddyHeadersOps{
+		Set: map[string][]string{
+			"Host":              {target.Domain},
+			"X-Forwarded-For":   {"{http.request.remote_host}"},
+			"X-Forwarded-Proto": {"{http.request.scheme}"},
+			"X-Real-IP":         {"{http.request.remote_host}"},
+		},
+	}
+	applyHeaderOps(request, target.RequestHeaders)
+
+	headers := &CaddyHeaders{
+		Request: request,
+	}
+
+	response := &CaddyHeadersOps{}
+	applyHeaderOps(response, target.ResponseHeaders)
+	if len(response.Set) > 0 || len(response.Add) > 0 || len(response.Delete) > 0 {
+		headers.Response = response
+	}
+
+	return headers
+}
+
+func applyHeaderOps(ops *CaddyHeadersOps, labels docker.HeaderOps) {
+	for _, name := range labels.Delete {
+		delete(ops.Set, name)
+		ops.Delete = append(ops.Delete, name)
+	}
+	for name, value := range labels.Set {
+		if ops.Set == nil {
+			ops.Set = make(map[string][]string)
 		}
+		ops.Set[name] = []string{value}
+	}
+	for name, value := range labels.Add {
+		if ops.Add == nil {
+			ops.Add = make(map[string][]string)
+		}
+		ops.Add[name] = []string{value}
+	}
+}
 
-		routes = append(routes, route)
+// generateTransport returns the reverse proxy transport for non-default
+// upstream schemes or timeouts, or nil for plain HTTP/1.1 with defaults.
+func (g *ConfigGenerator) generateTransport(target docker.ProxyTarget) *CaddyTransport {
+	transport := &CaddyTransport{
+		Protocol:              "http",
+		DialTimeout:           formatDuration(target.DialTimeout),
+		ResponseHeaderTimeout: formatDuration(target.ResponseHeaderTimeout),
+		ReadTimeout:           formatDuration(target.ReadTimeout),
+		WriteTimeout:          formatDuration(target.WriteTimeout),
 	}
 
-	return routes
+	switch target.Scheme {
+	case docker.SchemeH2C:
+		transport.Versions = []string{"h2c", "2"}
+	case docker.SchemeHTTPS:
+		tls := &CaddyTransportTLS{}
+		if target.UpstreamTLS != nil {
+			tls.ServerName = target.UpstreamTLS.ServerName
+			tls.InsecureSkipVerify = target.UpstreamTLS.InsecureSkipVerify
+			if target.UpstreamTLS.CAFile != "" {
+				tls.RootCAPEMFiles = []string{target.UpstreamTLS.CAFile}
+			}
+		}
+		if len(tls.RootCAPEMFiles) == 0 && g.config.DevProxy.UpstreamCA != "" {
+			tls.RootCAPEMFiles = []string{g.config.DevProxy.UpstreamCA}
+		}
+		transport.TLS = tls
+	}
+
+	if transport.Versions == nil && transport.TLS == nil && transport.DialTimeout == "" &&
+		transport.ResponseHeaderTimeout == "" && transport.ReadTimeout == "" && transport.WriteTimeout == "" {
+		return nil
+	}
+	return transport
+}
+
+func (g *ConfigGenerator) generateRedirectRoute(target docker.ProxyTarget) CaddyRoute {
+	return CaddyRoute{
+		Match: g.generateMatch(target),
+		Handle: []CaddyHandler{
+			{
+				Handler: "headers",
+				Response: &CaddyHeadersOps{
+					Set: map[string][]string{
+						"Location": {g.config.DevProxy.URL("https", "{http.request.host}") + "{http.request.uri}"},
+					},
+				},
+			},
+			{
+				Handler:    "static_response",
+				StatusCode: "308",
+			},
+		},
+		Terminal: true,
+	}
 }
 
 func (g *ConfigGenerator) SerializeConfig(config *CaddyConfig) ([]byte, error) {
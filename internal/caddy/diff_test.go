@@ -0,0 +1,162 @@
+package caddy
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string // lines separated by spaces
+		want string
+	}{
+		{name: "both empty"},
+		{name: "identical", a: "a b c", b: "a b c"},
+		{
+			name: "empty from",
+			b:    "a b",
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "empty to",
+			a:    "a b",
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "change at the start",
+			a:    "a b c d e f g h",
+			b:    "X b c d e f g h",
+			want: "@@ -1,4 +1,4 @@\n-a\n+X\n b\n c\n d\n",
+		},
+		{
+			name: "change at the end",
+			a:    "a b c d e f g h",
+			b:    "a b c d e f g X",
+			want: "@@ -5,4 +5,4 @@\n e\n f\n g\n-h\n+X\n",
+		},
+		{
+			name: "insertion in the middle",
+			a:    "a b c d e f g h",
+			b:    "a b c d X e f g h",
+			want: "@@ -2,6 +2,7 @@\n b\n c\n d\n+X\n e\n f\n g\n",
+		},
+		{
+			name: "changes merged across diffContext*2 unchanged lines",
+			a:    "a 1 2 3 4 5 6 b",
+			b:    "A 1 2 3 4 5 6 B",
+			want: "@@ -1,8 +1,8 @@\n-a\n+A\n 1\n 2\n 3\n 4\n 5\n 6\n-b\n+B\n",
+		},
+		{
+			name: "changes split by one more unchanged line",
+			a:    "a 1 2 3 4 5 6 7 b",
+			b:    "A 1 2 3 4 5 6 7 B",
+			want: "@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n" +
+				"@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name: "line counts differ between sides",
+			a:    "a b c d e f g h i j k l",
+			b:    "a b X Y c d e f g h i l",
+			want: "@@ -1,5 +1,7 @@\n a\n b\n+X\n+Y\n c\n d\n e\n" +
+				"@@ -7,6 +9,4 @@\n g\n h\n i\n-j\n-k\n l\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = "--- from\n+++ to\n" + want
+			}
+
+			got := diffLines(strings.Fields(tt.a), strings.Fields(tt.b), "from", "to")
+			if got != want {
+				t.Errorf("diffLines() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestComputeOps(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string // lines separated by spaces
+	}{
+		{name: "empty from", b: "a b c"},
+		{name: "empty to", a: "a b c"},
+		{name: "replaced", a: "a b c", b: "x y z"},
+		{name: "even delta", a: "a b c a b b a", b: "c b a b a c"},
+		{name: "odd delta", a: "a b c a b b a", b: "c b a b a"},
+		{name: "odd delta with a longer to", a: "x a y", b: "a b a c a"},
+		{name: "repeated lines", a: "a a a b a a", b: "a b a a a a a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOps(t, strings.Fields(tt.a), strings.Fields(tt.b))
+		})
+	}
+
+	// Small alphabets make many equally long edit paths
+	random := rand.New(rand.NewSource(1))
+	for range 500 {
+		a := randomLines(random, random.Intn(30))
+		b := randomLines(random, random.Intn(30))
+		checkOps(t, a, b)
+	}
+}
+
+// checkOps checks the edit script turns a into b and keeps a longest common
+// subsequence unchanged
+func checkOps(t *testing.T, a, b []string) {
+	t.Helper()
+
+	var from, to []string
+	unchanged := 0
+	for _, op := range computeOps(a, b) {
+		if op.kind != '+' {
+			from = append(from, op.line)
+		}
+		if op.kind != '-' {
+			to = append(to, op.line)
+		}
+		if op.kind == ' ' {
+			unchanged++
+		}
+	}
+
+	if !slices.Equal(from, a) || !slices.Equal(to, b) {
+		t.Fatalf("computeOps(%v, %v) turns %v into %v", a, b, from, to)
+	}
+	if want := lcsLength(a, b); unchanged != want {
+		t.Errorf("computeOps(%v, %v) keeps %d lines, want %d", a, b, unchanged, want)
+	}
+}
+
+func lcsLength(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+func randomLines(random *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a' + random.Intn(3)))
+	}
+	return lines
+}
@@ -0,0 +1,97 @@
+package caddy
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"devproxy/internal/docker"
+)
+
+// Page templates; a file with the same name in the templates directory
+// replaces the built-in one
+const (
+	pageError    = "error.html"     // upstream did not respond
+	pageNotFound = "not_found.html" // no route for the domain
+	pageStopped  = "stopped.html"   // container is not running
+
+	// Served by the manager while it starts a container on request
+	PageStarting = "starting.html"
+)
+
+//go:embed templates/*.html
+var builtinPages embed.FS
+
+// PageData is available to every page template. Values may contain Caddy
+// placeholders, which Caddy replaces when serving the page.
+type PageData struct {
+	Domain         string
+	ContainerName  string
+	ContainerState string
+	Upstream       string
+	StatusCode     string
+	DashboardURL   string
+
+	// Only set for stopped containers
+	StoppedAt    time.Time
+	ExitCode     int
+	StartCommand string
+	Crash        *docker.CrashReport
+}
+
+// Pages renders the HTML pages Caddy serves on behalf of DevProxy
+type Pages struct {
+	templates map[string]*template.Template
+}
+
+// LoadPages parses the built-in page templates, replacing those overridden
+// by files in dir. An empty dir uses the built-in pages only.
+func LoadPages(dir string) (*Pages, error) {
+	pages := &Pages{
+		templates: make(map[string]*template.Template),
+	}
+
+	for _, name := range []string{pageError, pageNotFound, pageStopped, PageStarting} {
+		source, err := builtinPages.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		if dir != "" {
+			override, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				source = override
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read page template %s: %w", name, err)
+			}
+		}
+
+		tmpl, err := template.New(name).Parse(string(source))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse page template %s: %w", name, err)
+		}
+		pages.templates[name] = tmpl
+	}
+
+	return pages, nil
+}
+
+// Render executes the named page template
+func (p *Pages) Render(name string, data PageData) (string, error) {
+	tmpl, exists := p.templates[name]
+	if !exists {
+		return "", fmt.Errorf("unknown page template %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render page template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
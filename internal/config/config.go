@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -15,6 +17,89 @@ type DevProxyConfig struct {
 	LogLevel      string
 	CaddyAdminURL string
 	DomainSuffix  string
+	UpstreamCA    string
+
+	// Credentials protecting every route, in htpasswd "user:bcrypt" format
+	BasicAuthUsers     string
+	BasicAuthUsersFile string
+
+	// Client address ranges allowed on routes without a devproxy.allow label
+	Allow string
+
+	// Caddy listeners
+	BindAddr          string
+	HTTPPort          int
+	HTTPSPort         int
+	Protocols         []string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// How long Caddy remembers a failed upstream request, zero disables
+	// failure counting
+	PassiveFailDuration time.Duration
+
+	// Failures within PassiveFailDuration that take an upstream out of
+	// rotation, zero only counts them
+	PassiveMaxFails int
+
+	// Directory with page templates overriding the built-in ones
+	TemplatesDir string
+
+	// Keep routes of stopped containers, serving a "service stopped" page
+	KeepStopped bool
+
+	// Number of log lines shown on the page of a crashed container
+	CrashLogLines int
+
+	// Address of the manager's HTTP server, and how Caddy reaches it
+	Addr        string
+	ManagerDial string
+
+	// How long a container started on request may take to accept connections
+	AutostartTimeout time.Duration
+
+	// Address the manager receives Caddy access logs on, and how Caddy
+	// reaches it
+	AccessLogAddr string
+	AccessLogDial string
+
+	// URL of the manager's HTTP server, used by the dashboard
+	ManagerURL string
+
+	// JSON file with routes to upstreams outside Docker
+	RoutesFile string
+
+	// Bearer token of the route registration API, which is disabled when empty
+	APIToken string
+
+	// Route dev servers listening on the host, found through its /proc
+	HostScan         bool
+	HostProc         string
+	HostScanInclude  []string
+	HostScanExclude  []string
+	HostScanUpstream string
+	HostScanInterval time.Duration
+
+	// JSON file listing the Docker hosts to watch, the environment's one
+	// when empty
+	DockerEndpointsFile string
+
+	// How Caddy reaches containers: "ip" dials the container's IP address,
+	// "published" the port it publishes on PublishedHost and "dns" its name
+	// on one of CaddyNetworks. The endpoints file can set them per Docker
+	// host.
+	UpstreamMode  string
+	PublishedHost string
+	CaddyNetworks []string
+
+	// IP family containers are dialed over, "v4", "v6" or "either", by
+	// network name; "*" holds the family of networks not listed
+	IPFamily map[string]string
+
+	// Order in which providers claim routes, earlier ones win conflicts
+	ProviderPrecedence []string
 }
 
 type DashboardConfig struct {
@@ -22,6 +107,7 @@ type DashboardConfig struct {
 	ExcludedProjects []string
 	ShowAllProjects  bool
 	Addr             string
+	Domain           string
 }
 
 // Load configuration from environment variables with sensible defaults
@@ -31,16 +117,78 @@ func Load() *Config {
 			LogLevel:      getEnv("DEVPROXY_LOG_LEVEL", "info"),
 			CaddyAdminURL: getEnv("CADDY_ADMIN_URL", "http://localhost:2019"),
 			DomainSuffix:  getEnv("DEVPROXY_DOMAIN_SUFFIX", "localhost"),
+			UpstreamCA:    getEnv("DEVPROXY_UPSTREAM_CA", ""),
+
+			BasicAuthUsers:     getEnv("DEVPROXY_AUTH_BASIC_USERS", ""),
+			BasicAuthUsersFile: getEnv("DEVPROXY_AUTH_BASIC_USERS_FILE", ""),
+
+			Allow: getEnv("DEVPROXY_ALLOW", ""),
+
+			BindAddr:          getEnv("DEVPROXY_BIND_ADDR", ""),
+			HTTPPort:          getEnvInt("DEVPROXY_HTTP_PORT", 80),
+			HTTPSPort:         getEnvInt("DEVPROXY_HTTPS_PORT", 443),
+			Protocols:         getEnvList("DEVPROXY_PROTOCOLS", []string{"h1", "h2", "h3"}),
+			ReadTimeout:       getEnvDuration("DEVPROXY_READ_TIMEOUT", 0),
+			ReadHeaderTimeout: getEnvDuration("DEVPROXY_READ_HEADER_TIMEOUT", 0),
+			WriteTimeout:      getEnvDuration("DEVPROXY_WRITE_TIMEOUT", 0),
+			IdleTimeout:       getEnvDuration("DEVPROXY_IDLE_TIMEOUT", 0),
+
+			PassiveFailDuration: getEnvDuration("DEVPROXY_PASSIVE_FAIL_DURATION", 10*time.Second),
+			PassiveMaxFails:     getEnvInt("DEVPROXY_PASSIVE_MAX_FAILS", 0),
+
+			TemplatesDir:  getEnv("DEVPROXY_TEMPLATES_DIR", ""),
+			KeepStopped:   getEnvBool("DEVPROXY_KEEP_STOPPED", false),
+			CrashLogLines: getEnvInt("DEVPROXY_CRASH_LOG_LINES", 50),
+
+			Addr:             getEnv("DEVPROXY_ADDR", ":8081"),
+			ManagerDial:      getEnv("DEVPROXY_MANAGER_DIAL", "devproxy-manager:8081"),
+			AutostartTimeout: getEnvDuration("DEVPROXY_AUTOSTART_TIMEOUT", 60*time.Second),
+
+			AccessLogAddr: getEnv("DEVPROXY_ACCESS_LOG_ADDR", ":8082"),
+			AccessLogDial: getEnv("DEVPROXY_ACCESS_LOG_DIAL", "devproxy-manager:8082"),
+			ManagerURL:    getEnv("DEVPROXY_MANAGER_URL", "http://devproxy-manager:8081"),
+
+			RoutesFile: getEnv("DEVPROXY_ROUTES_FILE", ""),
+			APIToken:   getEnv("DEVPROXY_API_TOKEN", ""),
+
+			HostScan:         getEnvBool("DEVPROXY_HOST_SCAN", false),
+			HostProc:         getEnv("DEVPROXY_HOST_PROC", "/proc"),
+			HostScanInclude:  getEnvList("DEVPROXY_HOST_SCAN_INCLUDE", nil),
+			HostScanExclude:  getEnvList("DEVPROXY_HOST_SCAN_EXCLUDE", []string{"docker-proxy", "dockerd", "containerd", "sshd", "systemd-resolved", "cupsd"}),
+			HostScanUpstream: getEnv("DEVPROXY_HOST_SCAN_UPSTREAM", "host.docker.internal"),
+			HostScanInterval: getEnvDuration("DEVPROXY_HOST_SCAN_INTERVAL", 5*time.Second),
+
+			DockerEndpointsFile: getEnv("DEVPROXY_DOCKER_ENDPOINTS_FILE", ""),
+			UpstreamMode:        getEnv("DEVPROXY_UPSTREAM_MODE", "ip"),
+			PublishedHost:       getEnv("DEVPROXY_PUBLISHED_HOST", "host.docker.internal"),
+			CaddyNetworks:       getEnvList("DEVPROXY_CADDY_NETWORKS", []string{"devproxy"}),
+			IPFamily:            getEnvMap("DEVPROXY_IP_FAMILY", "*", map[string]string{"*": "either"}),
+
+			ProviderPrecedence: getEnvList("DEVPROXY_PROVIDER_PRECEDENCE", []string{"api", "static", "docker", "host"}),
 		},
 		Dashboard: DashboardConfig{
 			RefreshInterval:  getEnvInt("DEVPROXY_DASHBOARD_REFRESH", 30),
 			ExcludedProjects: getEnvList("DEVPROXY_DASHBOARD_EXCLUDE", []string{"devproxy"}),
 			ShowAllProjects:  getEnvBool("DEVPROXY_DASHBOARD_SHOW_ALL", false),
 			Addr:             getEnv("DASHBOARD_ADDR", ":8080"),
+			Domain:           getEnv("DEVPROXY_DASHBOARD_DOMAIN", "devproxy-dashboard.localhost"),
 		},
 	}
 }
 
+// URL returns the public URL of a proxied host, including the port when it
+// is not the default one for the scheme
+func (c DevProxyConfig) URL(scheme, host string) string {
+	switch {
+	case scheme == "https" && c.HTTPSPort != 443:
+		return fmt.Sprintf("https://%s:%d", host, c.HTTPSPort)
+	case scheme == "http" && c.HTTPPort != 80:
+		return fmt.Sprintf("http://%s:%d", host, c.HTTPPort)
+	default:
+		return fmt.Sprintf("%s://%s", scheme, host)
+	}
+}
+
 // getEnv gets environment variable with default fallback
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -81,3 +229,30 @@ func getEnvList(key string, defaultValue []string) []string {
 	}
 	return defaultValue
 }
+
+// getEnvMap gets environment variable as comma-separated key=value pairs with
+// default fallback. Values without a key are stored under defaultKey.
+func getEnvMap(key, defaultKey string, defaultValue map[string]string) map[string]string {
+	if value := os.Getenv(key); value != "" {
+		result := make(map[string]string)
+		for _, item := range strings.Split(value, ",") {
+			k, v, found := strings.Cut(strings.TrimSpace(item), "=")
+			if !found {
+				k, v = defaultKey, k
+			}
+			result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets environment variable as duration with default fallback
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durationVal, err := time.ParseDuration(value); err == nil {
+			return durationVal
+		}
+	}
+	return defaultValue
+}
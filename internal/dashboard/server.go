@@ -7,35 +7,46 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"devproxy/internal/caddy"
 	"devproxy/internal/config"
 	"devproxy/internal/docker"
+	"devproxy/internal/hostscan"
 	"devproxy/internal/proxy"
+	"devproxy/internal/static"
 )
 
 type Server struct {
-	config  *config.Config
-	manager *proxy.Manager
-	logger  *slog.Logger
+	config        *config.Config
+	manager       *proxy.Manager
+	managerClient *proxy.Client
+	logger        *slog.Logger
 }
 
 type ContainerInfo struct {
-	ID       string               `json:"id"`
-	Name     string               `json:"name"`
-	Image    string               `json:"image"`
-	Status   string               `json:"status"`
-	Targets  []docker.ProxyTarget `json:"targets"`
-	Protocol string               `json:"protocol"`
-	Project  string               `json:"project"`
-	Service  string               `json:"service"`
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Image     string                `json:"image"`
+	Status    string                `json:"status"`
+	Targets   []docker.ProxyTarget  `json:"targets"`
+	Protocol  string                `json:"protocol"`
+	Project   string                `json:"project"`
+	Service   string                `json:"service"`
+	Health    string                `json:"health,omitempty"`
+	Upstreams []proxy.UpstreamState `json:"upstreams,omitempty"`
+	Idle      *proxy.IdleState      `json:"idle,omitempty"`
+	Source    string                `json:"source"`
+	Host      string                `json:"host,omitempty"`
 }
 
 func NewServer(config *config.Config, manager *proxy.Manager, logger *slog.Logger) *Server {
 	return &Server{
-		config:  config,
-		manager: manager,
-		logger:  logger,
+		config:        config,
+		manager:       manager,
+		managerClient: proxy.NewClient(config.DevProxy.ManagerURL, logger),
+		logger:        logger,
 	}
 }
 
@@ -43,6 +54,11 @@ func (s *Server) Start(ctx context.Context, addr string) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleDashboard)
 	mux.HandleFunc("/api/containers", s.handleAPIContainers)
+	mux.HandleFunc("/api/render", s.handleAPIRender)
+	mux.HandleFunc("/api/conflicts", s.handleAPIConflicts)
+	mux.HandleFunc("/api/upstreams", s.handleAPIUpstreams)
+	mux.HandleFunc("/api/providers", s.handleAPIProviders)
+	mux.HandleFunc("/api/idle/exempt", s.handleAPIIdleExempt)
 
 	server := &http.Server{
 		Addr:    addr,
@@ -69,8 +85,14 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	// Prepare template data
 	data := struct {
 		RefreshInterval int
+		HTTPPort        int
+		HTTPSPort       int
+		DashboardURL    string
 	}{
 		RefreshInterval: s.config.Dashboard.RefreshInterval * 1000, // Convert to milliseconds
+		HTTPPort:        s.config.DevProxy.HTTPPort,
+		HTTPSPort:       s.config.DevProxy.HTTPSPort,
+		DashboardURL:    s.config.DevProxy.URL("https", s.config.Dashboard.Domain),
 	}
 
 	tmpl := `<!DOCTYPE html>
@@ -226,6 +248,39 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
         .install-instructions li {
             margin: 5px 0;
         }
+        .conflicts {
+            margin-bottom: 25px;
+            padding: 15px;
+            border-radius: 8px;
+            background: #fff3cd;
+            border: 1px solid #ffeeba;
+            color: #856404;
+            display: none;
+        }
+        .conflicts.show {
+            display: block;
+        }
+        .conflicts ul {
+            margin: 8px 0 0 0;
+            padding-left: 20px;
+        }
+        .providers {
+            margin-bottom: 15px;
+            font-size: 0.85em;
+            color: #6c757d;
+        }
+        .provider-badge {
+            display: inline-block;
+            margin-right: 6px;
+            padding: 2px 8px;
+            border-radius: 10px;
+            background: #d4edda;
+            color: #155724;
+        }
+        .provider-badge.unhealthy {
+            background: #f8d7da;
+            color: #721c24;
+        }
         .project-group {
             margin-bottom: 25px;
             background: white;
@@ -298,6 +353,8 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             margin-right: 12px;
         }
         .status-running { background: #28a745; }
+        .status-static { background: #17a2b8; }
+        .status-host { background: #6f42c1; }
         .status-starting { background: #ffc107; }
         .status-stopped { background: #dc3545; }
         .container-info {
@@ -313,6 +370,41 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             font-size: 0.85em;
             color: #6c757d;
         }
+        .tls-badge {
+            background: #e9ecef;
+            color: #495057;
+            padding: 1px 6px;
+            border-radius: 4px;
+            font-size: 0.85em;
+            margin-left: 6px;
+        }
+        .idle-button {
+            background: none;
+            border: 1px solid #ced4da;
+            color: #495057;
+            padding: 0 6px;
+            border-radius: 4px;
+            font-size: 0.8em;
+            margin-left: 4px;
+            cursor: pointer;
+        }
+        .idle-button:hover {
+            background: #e9ecef;
+        }
+        .health-badge {
+            padding: 1px 6px;
+            border-radius: 4px;
+            font-size: 0.85em;
+            margin-left: 6px;
+        }
+        .health-healthy {
+            background: #d4edda;
+            color: #155724;
+        }
+        .health-unhealthy {
+            background: #f8d7da;
+            color: #721c24;
+        }
         .container-actions {
             display: flex;
             gap: 8px;
@@ -378,6 +470,8 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
         let filteredContainers = [];
         let currentFilter = 'all';
         let searchQuery = '';
+        const httpPort = {{.HTTPPort}};
+        const httpsPort = {{.HTTPSPort}};
 
         function loadProtocolStatus() {
             const statusDiv = document.getElementById('protocol-status');
@@ -392,7 +486,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
                         '<ol>' +
                             '<li>Run: <code>./trust-cert.sh</code></li>' +
                             '<li>Restart your browser</li>' +
-                            '<li>Access: <a href="https://devproxy-dashboard.localhost">https://devproxy-dashboard.localhost</a></li>' +
+                            '<li>Access: <a href="{{.DashboardURL}}">{{.DashboardURL}}</a></li>' +
                         '</ol>' +
                         '<p><strong>Note:</strong> The script works on macOS, Linux, and Windows.</p>' +
                     '</div>';
@@ -411,11 +505,73 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
                 .catch(err => console.error('Failed to load containers:', err));
         }
 
+        function loadConflicts() {
+            fetch('/api/conflicts')
+                .then(response => response.json())
+                .then(conflicts => renderConflicts(conflicts))
+                .catch(err => console.error('Failed to load conflicts:', err));
+        }
+
+        // Names, domains and addresses come from labels and the routes file,
+        // so they are escaped before going into HTML
+        function escapeHtml(value) {
+            const entities = { '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' };
+            return String(value ?? '').replace(/[&<>"']/g, ch => entities[ch]);
+        }
+
+        function renderConflicts(conflicts) {
+            const conflictsDiv = document.getElementById('conflicts');
+            if (!conflicts || conflicts.length === 0) {
+                conflictsDiv.className = 'conflicts';
+                conflictsDiv.innerHTML = '';
+                return;
+            }
+
+            let html = '⚠️ Some domains are claimed by several containers:<ul>';
+            conflicts.forEach(c => {
+                const route = c.Domain + (c.Path || '');
+                const ignored = c.Losers.map(l => l.ContainerName).join(', ');
+                html += '<li><strong>' + escapeHtml(route) + '</strong> routed to <strong>' + escapeHtml(c.Winner.ContainerName) + '</strong>, ignored: ' + escapeHtml(ignored) + '</li>';
+            });
+            html += '</ul>';
+
+            conflictsDiv.className = 'conflicts show';
+            conflictsDiv.innerHTML = html;
+        }
+
+        function loadProviders() {
+            fetch('/api/providers')
+                .then(response => response.ok ? response.json() : [])
+                .then(providers => renderProviders(providers))
+                .catch(err => console.error('Failed to load providers:', err));
+        }
+
+        function renderProviders(providers) {
+            const providersDiv = document.getElementById('providers');
+            if (!providers || providers.length === 0) {
+                providersDiv.innerHTML = '';
+                return;
+            }
+
+            providersDiv.textContent = 'Sources: ';
+            providers.forEach(p => {
+                // Errors are set as text, they may contain markup
+                const badge = document.createElement('span');
+                badge.className = 'provider-badge' + (p.healthy ? '' : ' unhealthy');
+                badge.title = p.healthy ? p.targets + ' routes' : p.error;
+                badge.textContent = p.name + ' (' + p.targets + ')';
+                providersDiv.appendChild(badge);
+            });
+        }
+
         function applyFilters() {
             filteredContainers = allContainers.filter(c => {
                 // Status filter
-                if (currentFilter === 'running' && c.status !== 'running') return false;
-                if (currentFilter === 'stopped' && c.status === 'running') return false;
+                // Static routes are always up as far as DevProxy can tell,
+                // host dev servers are only listed while listening
+                const up = c.status === 'running' || c.source === 'static' || c.source === 'host';
+                if (currentFilter === 'running' && !up) return false;
+                if (currentFilter === 'stopped' && up) return false;
 
                 // Search filter
                 if (searchQuery) {
@@ -424,6 +580,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
                         c.name || '',
                         c.service || '',
                         c.project || '',
+                        c.host || '',
                         c.image || '',
                         ...(c.targets?.map(t => t.Domain) || [])
                     ].join(' ').toLowerCase();
@@ -435,42 +592,47 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             });
         }
 
-        function renderSidebar() {
-            const sidebar = document.getElementById('sidebar-content');
-
-            // Group containers by project for sidebar
-            const grouped = {};
-            const standalone = [];
+        // Containers are grouped by Docker host, then by compose project.
+        // Group names carry the host when there are several, as the same
+        // project can run on each of them.
+        function groupContainers(containers) {
+            const hosts = new Set(allContainers.filter(c => c.host).map(c => c.host));
+            const groups = new Map();
 
-            filteredContainers.forEach(c => {
-                if (c.project) {
-                    if (!grouped[c.project]) {
-                        grouped[c.project] = [];
+            containers.forEach(c => {
+                const host = c.host || '';
+                const project = c.project || '';
+                // Encoding each part keeps ids of different groups apart
+                const id = 'project-' + encodeURIComponent(host) + '/' + encodeURIComponent(project);
+                if (!groups.has(id)) {
+                    let name = project || 'Standalone';
+                    if (host && hosts.size > 1) {
+                        name = host + ' / ' + name;
                     }
-                    grouped[c.project].push(c);
-                } else {
-                    standalone.push(c);
+                    groups.set(id, { id, host, project, name, containers: [] });
                 }
+                groups.get(id).containers.push(c);
             });
 
+            // Each host's standalone containers come after its projects
+            return [...groups.values()].sort((a, b) =>
+                a.host.localeCompare(b.host) ||
+                (a.project === '') - (b.project === '') ||
+                a.project.localeCompare(b.project));
+        }
+
+        function renderSidebar() {
+            const sidebar = document.getElementById('sidebar-content');
+
             let html = '<h2>Navigation</h2>';
 
-            // Projects
-            Object.keys(grouped).sort().forEach(projectName => {
-                html += '<div class="nav-item project" onclick="scrollToProject(\'' + projectName + '\')">';
-                html += '🐳 ' + projectName;
-                html += '<span class="nav-count">' + grouped[projectName].length + '</span>';
+            groupContainers(filteredContainers).forEach(group => {
+                html += '<div class="nav-item project" data-group="' + escapeHtml(group.id) + '" onclick="scrollToProject(this.dataset.group)">';
+                html += (group.project ? '🐳 ' : '📦 ') + escapeHtml(group.name);
+                html += '<span class="nav-count">' + group.containers.length + '</span>';
                 html += '</div>';
             });
 
-            // Standalone containers
-            if (standalone.length > 0) {
-                html += '<div class="nav-item project" onclick="scrollToProject(\'standalone\')">';
-                html += '📦 Standalone';
-                html += '<span class="nav-count">' + standalone.length + '</span>';
-                html += '</div>';
-            }
-
             sidebar.innerHTML = html;
         }
 
@@ -481,55 +643,32 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
                 return;
             }
 
-            // Group containers by project
-            const grouped = {};
-            const standalone = [];
-
-            filteredContainers.forEach(c => {
-                if (c.project) {
-                    if (!grouped[c.project]) {
-                        grouped[c.project] = [];
-                    }
-                    grouped[c.project].push(c);
-                } else {
-                    standalone.push(c);
-                }
-            });
-
             let html = '';
-
-            // Render compose projects
-            Object.keys(grouped).sort().forEach(projectName => {
-                html += renderProjectGroup(projectName, grouped[projectName], '🐳', 'Compose Project');
+            groupContainers(filteredContainers).forEach(group => {
+                html += renderProjectGroup(group);
             });
 
-            // Render standalone containers
-            if (standalone.length > 0) {
-                html += renderProjectGroup('standalone', standalone, '📦', 'Standalone Containers');
-            }
-
             container.innerHTML = html;
         }
 
-        function renderProjectGroup(projectName, containers, icon, subtitle) {
-            const projectId = 'project-' + projectName.replace(/[^a-zA-Z0-9]/g, '');
-            const isCollapsed = localStorage.getItem(projectId + '-collapsed') === 'true';
+        function renderProjectGroup(group) {
+            const isCollapsed = localStorage.getItem(group.id + '-collapsed') === 'true';
+            const icon = group.project ? '🐳' : '📦';
+            const subtitle = group.project ? 'Compose Project' : 'Standalone Containers';
 
-            let html = '<div class="project-group' + (isCollapsed ? ' collapsed' : '') + '" id="' + projectId + '">';
-            html += '<div class="project-header" onclick="toggleProject(\'' + projectId + '\')">';
-            html += '<div class="project-title">' + icon + ' ' + projectName;
-            if (subtitle !== projectName) {
-                html += ' <span style="font-weight: normal; color: #6c757d;">(' + subtitle + ')</span>';
-            }
+            let html = '<div class="project-group' + (isCollapsed ? ' collapsed' : '') + '" id="' + escapeHtml(group.id) + '">';
+            html += '<div class="project-header" onclick="toggleProject(this.parentElement.id)">';
+            html += '<div class="project-title">' + icon + ' ' + escapeHtml(group.name);
+            html += ' <span style="font-weight: normal; color: #6c757d;">(' + subtitle + ')</span>';
             html += '</div>';
             html += '<div class="project-controls">';
-            html += '<span class="container-count">' + containers.length + ' service' + (containers.length !== 1 ? 's' : '') + '</span>';
+            html += '<span class="container-count">' + group.containers.length + ' service' + (group.containers.length !== 1 ? 's' : '') + '</span>';
             html += '<span class="collapse-icon">▼</span>';
             html += '</div>';
             html += '</div>';
 
             html += '<div class="containers-table">';
-            containers.forEach(c => {
+            group.containers.forEach(c => {
                 html += renderContainerRow(c);
             });
             html += '</div>';
@@ -538,27 +677,68 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             return html;
         }
 
+        function targetProtocol(target) {
+            // Follow the route's TLS mode, falling back to the dashboard's own protocol
+            switch (target && target.TLSMode) {
+                case 'http-only':
+                    return 'http';
+                case 'https-only':
+                case 'redirect':
+                    return 'https';
+                default:
+                    return currentProtocol.replace(':', '');
+            }
+        }
+
+        function targetURL(target) {
+            // Include the port when Caddy does not listen on the default one
+            const protocol = targetProtocol(target);
+            const port = protocol === 'https' ? httpsPort : httpPort;
+            const defaultPort = protocol === 'https' ? 443 : 80;
+            const host = port === defaultPort ? target.Domain : target.Domain + ':' + port;
+            return protocol + '://' + host + (target.Path || '');
+        }
+
         function renderContainerRow(c) {
-            const protocol = currentProtocol.replace(':', '');
+            const primaryTarget = c.targets && c.targets.length > 0 ? c.targets[0] : null;
             const displayName = c.service || c.name || 'Unknown';
-            const primaryDomain = c.targets && c.targets.length > 0 ? c.targets[0].Domain : '';
-            const statusClass = 'status-' + (c.status === 'running' ? 'running' : c.status === 'starting' ? 'starting' : 'stopped');
+            const primaryURL = primaryTarget ? targetURL(primaryTarget) : '';
+            const statusClass = 'status-' + (c.source === 'static' || c.source === 'host' ? c.source : c.status === 'running' ? 'running' : c.status === 'starting' ? 'starting' : 'stopped');
 
             let html = '<div class="container-row">';
             html += '<div class="status-indicator ' + statusClass + '"></div>';
             html += '<div class="container-info">';
-            html += '<div class="container-name">' + displayName + '</div>';
-            html += '<div class="container-meta">' + (c.image || 'Unknown image');
+            html += '<div class="container-name">' + escapeHtml(displayName) + '</div>';
+            html += '<div class="container-meta">' + escapeHtml(c.image || 'Unknown image');
             if (c.service && c.name !== c.service) {
-                html += ' • ' + c.name;
+                html += ' • ' + escapeHtml(c.name);
+            }
+            if (c.source === 'static' || c.source === 'host') {
+                html += ' <span class="tls-badge">' + c.source + '</span>';
+            }
+            if (primaryTarget && primaryTarget.TLSMode) {
+                html += ' <span class="tls-badge">' + escapeHtml(primaryTarget.TLSMode) + '</span>';
+            }
+            if (c.health) {
+                html += ' <span class="health-badge health-' + c.health + '">' + c.health + '</span>';
+            }
+            const upstream = c.upstreams && c.upstreams.length > 0 ? c.upstreams[0] : null;
+            if (upstream && upstream.state === 'failing') {
+                html += ' <span class="health-badge health-unhealthy" title="Caddy failed to reach ' + escapeHtml(upstream.address) + '">unreachable (' + upstream.fails + ' failures)</span>';
+            }
+            if (upstream && upstream.in_flight > 0) {
+                html += ' <span class="tls-badge">' + upstream.in_flight + ' in flight</span>';
+            }
+            if (c.idle && c.status === 'running') {
+                html += renderIdle(c.idle);
             }
             html += '</div>';
             html += '</div>';
 
-            if (primaryDomain) {
+            if (primaryURL) {
                 html += '<div class="container-actions">';
-                html += '<a href="' + protocol + '://' + primaryDomain + '" target="_blank" class="link-button">Open</a>';
-                html += '<button onclick="copyToClipboard(event, \'' + protocol + '://' + primaryDomain + '\')" class="copy-button">Copy</button>';
+                html += '<a href="' + escapeHtml(primaryURL) + '" target="_blank" class="link-button">Open</a>';
+                html += '<button data-url="' + escapeHtml(primaryURL) + '" onclick="copyToClipboard(event, this.dataset.url)" class="copy-button">Copy</button>';
                 html += '</div>';
             }
 
@@ -566,6 +746,40 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             return html;
         }
 
+        function formatCountdown(stopsAt) {
+            const seconds = Math.max(0, Math.round((new Date(stopsAt) - Date.now()) / 1000));
+            const hours = Math.floor(seconds / 3600);
+            const minutes = Math.floor(seconds % 3600 / 60);
+            if (hours > 0) return hours + 'h ' + minutes + 'm';
+            if (minutes > 0) return minutes + 'm ' + (seconds % 60) + 's';
+            return seconds + 's';
+        }
+
+        function renderIdle(idle) {
+            // Containers with devproxy.idle_timeout are stopped by the manager when unused
+            if (idle.exempt_until) {
+                return ' <span class="tls-badge" title="Not stopped when idle until ' + new Date(idle.exempt_until).toLocaleString() + '">awake today</span>' +
+                    '<button data-id="' + escapeHtml(idle.container_id) + '" onclick="setIdleExempt(this.dataset.id, false)" class="idle-button">Allow sleep</button>';
+            }
+            return ' <span class="tls-badge idle-countdown" data-stops-at="' + escapeHtml(idle.stops_at) + '" title="Stopped after ' + escapeHtml(idle.timeout) + ' without requests">sleeps in ' + formatCountdown(idle.stops_at) + '</span>' +
+                '<button data-id="' + escapeHtml(idle.container_id) + '" onclick="setIdleExempt(this.dataset.id, true)" class="idle-button">Keep awake today</button>';
+        }
+
+        function updateCountdowns() {
+            document.querySelectorAll('.idle-countdown').forEach(el => {
+                el.textContent = 'sleeps in ' + formatCountdown(el.dataset.stopsAt);
+            });
+        }
+
+        function setIdleExempt(containerID, exempt) {
+            fetch('/api/idle/exempt?id=' + encodeURIComponent(containerID), { method: exempt ? 'POST' : 'DELETE' })
+                .then(response => {
+                    if (!response.ok) throw new Error(response.statusText);
+                    loadContainers();
+                })
+                .catch(err => console.error('Failed to update idle exemption:', err));
+        }
+
         function toggleProject(projectId) {
             const projectGroup = document.getElementById(projectId);
             const isCollapsed = projectGroup.classList.contains('collapsed');
@@ -579,8 +793,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             }
         }
 
-        function scrollToProject(projectName) {
-            const projectId = 'project-' + projectName.replace(/[^a-zA-Z0-9]/g, '');
+        function scrollToProject(projectId) {
             const element = document.getElementById(projectId);
             if (element) {
                 element.scrollIntoView({ behavior: 'smooth', block: 'start' });
@@ -680,10 +893,15 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
         document.addEventListener('DOMContentLoaded', function() {
             loadProtocolStatus();
             loadContainers();
+            loadConflicts();
+            loadProviders();
             setInterval(function() {
                 loadProtocolStatus();
                 loadContainers();
+                loadConflicts();
+                loadProviders();
             }, {{.RefreshInterval}});
+            setInterval(updateCountdowns, 1000);
         });
     </script>
 </head>
@@ -700,6 +918,8 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
             <div class="header">
                 <h1>DevProxy Dashboard</h1>
                 <div id="protocol-status" class="protocol-status"></div>
+                <div id="providers" class="providers"></div>
+                <div id="conflicts" class="conflicts"></div>
 
                 <div class="search-container">
                     <input type="text" class="search-input" placeholder="Search containers, projects, domains..."
@@ -743,26 +963,111 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleAPIContainers(w http.ResponseWriter, r *http.Request) {
+	// Upstream status is best effort, the list is still useful without it
+	upstreams, err := s.manager.GetUpstreams(r.Context())
+	if err != nil {
+		s.logger.Warn("Failed to get upstream status from Caddy", "error", err)
+	}
 
-	// Get containers directly from Docker since the dashboard manager isn't started
-	dockerContainers, err := s.manager.GetRunningContainers(context.Background())
+	// Idle shutdown is tracked by the manager process
+	idleStates := make(map[string]proxy.IdleState)
+	states, err := s.managerClient.GetIdleStates(r.Context())
 	if err != nil {
-		s.logger.Error("Failed to get running containers", "error", err)
+		s.logger.Warn("Failed to get idle states from the manager", "error", err)
+	}
+	for _, state := range states {
+		idleStates[state.ContainerID] = state
+	}
+
+	// Get containers directly from Docker since the dashboard manager isn't started
+	var containers []ContainerInfo
+	listed := false
+	for _, provider := range s.manager.DockerProviders() {
+		hostContainers, err := s.listDockerContainers(r.Context(), provider, upstreams, idleStates)
+		if err != nil {
+			s.logger.Error("Failed to get containers", "endpoint", provider.Endpoint(), "error", err)
+			continue
+		}
+		listed = true
+		containers = append(containers, hostContainers...)
+	}
+	if !listed {
 		http.Error(w, "Failed to get containers", http.StatusInternalServerError)
 		return
 	}
 
+	// Static routes have no container, each one is listed on its own
+	for _, target := range s.manager.LoadStaticTargets() {
+		targets := []docker.ProxyTarget{target}
+		container := ContainerInfo{
+			Name:    target.Domain,
+			Image:   target.Dial(),
+			Status:  static.Source,
+			Targets: targets,
+			Project: static.Source,
+			Service: target.Domain + target.Path,
+			Source:  target.Source,
+		}
+		if upstreams != nil {
+			container.Upstreams = proxy.JoinUpstreams(targets, upstreams)
+		}
+		containers = append(containers, container)
+	}
+
+	// Host dev servers are found by the manager, which sees the host's /proc.
+	// A process's targets share one upstream and are listed together.
+	hostTargets, err := s.managerClient.GetHostTargets(r.Context())
+	if err != nil {
+		s.logger.Warn("Failed to get host dev servers from the manager", "error", err)
+	}
+	var hostDials []string
+	hostByDial := make(map[string][]docker.ProxyTarget)
+	for _, target := range hostTargets {
+		dial := target.Dial()
+		if _, seen := hostByDial[dial]; !seen {
+			hostDials = append(hostDials, dial)
+		}
+		hostByDial[dial] = append(hostByDial[dial], target)
+	}
+	for _, dial := range hostDials {
+		targets := hostByDial[dial]
+		container := ContainerInfo{
+			Name:    targets[0].ContainerName,
+			Image:   dial,
+			Status:  hostscan.Source,
+			Targets: targets,
+			Project: hostscan.Source,
+			Service: targets[0].Domain,
+			Source:  hostscan.Source,
+		}
+		if upstreams != nil {
+			container.Upstreams = proxy.JoinUpstreams(targets, upstreams)
+		}
+		containers = append(containers, container)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(containers)
+}
+
+// listDockerContainers lists the routed containers of one Docker host
+func (s *Server) listDockerContainers(ctx context.Context, provider *proxy.DockerProvider, upstreams []caddy.UpstreamStatus, idleStates map[string]proxy.IdleState) ([]ContainerInfo, error) {
+	dockerContainers, err := provider.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var containers []ContainerInfo
 	for _, dockerContainer := range dockerContainers {
 		// Inspect each container to get full details
-		containerInfo, err := s.manager.InspectContainer(context.Background(), dockerContainer.ID)
+		containerInfo, err := provider.InspectContainer(ctx, dockerContainer.ID)
 		if err != nil {
 			s.logger.Warn("Failed to inspect container", "container_id", dockerContainer.ID, "error", err)
 			continue
 		}
 
 		// Use discovery logic to extract proxy targets
-		targets := s.manager.GetDiscovery().ExtractProxyTargets(containerInfo)
+		targets := provider.ExtractTargets(containerInfo)
 		if len(targets) == 0 {
 			continue
 		}
@@ -814,11 +1119,152 @@ func (s *Server) handleAPIContainers(w http.ResponseWriter, r *http.Request) {
 				Protocol: "", // Will be determined by frontend based on current location
 				Project:  project,
 				Service:  service,
+				Source:   docker.SourceDocker,
+				Host:     provider.Endpoint(),
+			}
+			if upstreams != nil {
+				container.Upstreams = proxy.JoinUpstreams(targets, upstreams)
+			}
+			if state, exists := idleStates[containerInfo.ID]; exists {
+				container.Idle = &state
 			}
 			containers = append(containers, container)
 		}
 	}
 
+	// Health checks are probed concurrently, as slow upstreams wait for
+	// their timeout
+	var wg sync.WaitGroup
+	for i := range containers {
+		if containers[i].Status != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func(container *ContainerInfo) {
+			defer wg.Done()
+			container.Health = healthStatus(ctx, container.Targets)
+		}(&containers[i])
+	}
+	wg.Wait()
+
+	return containers, nil
+}
+
+// healthStatus runs the health check of the first health-checked target,
+// reporting "healthy" or "unhealthy", or "" when no target has one
+func healthStatus(ctx context.Context, targets []docker.ProxyTarget) string {
+	for _, target := range targets {
+		if target.HealthCheck == nil {
+			continue
+		}
+		if err := proxy.ProbeHealth(ctx, target); err != nil {
+			return "unhealthy"
+		}
+		return "healthy"
+	}
+
+	return ""
+}
+
+func (s *Server) handleAPIUpstreams(w http.ResponseWriter, r *http.Request) {
+	targets, err := s.manager.CurrentTargets(r.Context())
+	if err != nil {
+		s.logger.Error("Failed to get proxy targets", "error", err)
+		http.Error(w, "Failed to get proxy targets", http.StatusInternalServerError)
+		return
+	}
+
+	states, err := s.manager.GetUpstreamStates(r.Context(), targets)
+	if err != nil {
+		s.logger.Error("Failed to get upstream status from Caddy", "error", err)
+		http.Error(w, "Failed to get upstream status", http.StatusBadGateway)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(containers)
+	json.NewEncoder(w).Encode(states)
+}
+
+func (s *Server) handleAPIRender(w http.ResponseWriter, r *http.Request) {
+	rendered, err := s.manager.RenderCurrent(r.Context())
+	if err != nil {
+		s.logger.Error("Failed to render config", "error", err)
+		http.Error(w, "Failed to render config", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("diff") != "true" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rendered)
+		return
+	}
+
+	live, err := s.manager.LiveConfig(r.Context())
+	if err != nil {
+		s.logger.Error("Failed to get live Caddy config", "error", err)
+		http.Error(w, "Failed to get live Caddy config", http.StatusBadGateway)
+		return
+	}
+
+	diff, err := caddy.DiffConfigs(live, rendered, "caddy", "devproxy")
+	if err != nil {
+		s.logger.Error("Failed to diff configs", "error", err)
+		http.Error(w, "Failed to diff configs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(diff))
+}
+
+func (s *Server) handleAPIConflicts(w http.ResponseWriter, r *http.Request) {
+	conflicts, err := s.manager.CurrentConflicts(r.Context())
+	if err != nil {
+		s.logger.Error("Failed to get proxy targets", "error", err)
+		http.Error(w, "Failed to get proxy targets", http.StatusInternalServerError)
+		return
+	}
+
+	if conflicts == nil {
+		conflicts = []docker.Conflict{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conflicts)
+}
+
+// handleAPIProviders reports the health of the manager's providers
+func (s *Server) handleAPIProviders(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.managerClient.GetProviderStatuses(r.Context())
+	if err != nil {
+		s.logger.Warn("Failed to get provider statuses from the manager", "error", err)
+		http.Error(w, "Failed to get provider statuses", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// handleAPIIdleExempt keeps the container given by the id parameter from
+// idle shutdown for the rest of the day (POST), or lifts that (DELETE)
+func (s *Server) handleAPIIdleExempt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	containerID := r.URL.Query().Get("id")
+	if containerID == "" {
+		http.Error(w, "Missing container id", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.managerClient.SetIdleExempt(r.Context(), containerID, r.Method == http.MethodPost); err != nil {
+		s.logger.Error("Failed to update idle exemption", "container_id", containerID, "error", err)
+		http.Error(w, "Failed to update idle exemption", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+var (
+	loopbackRanges = []string{"127.0.0.0/8", "::1/128"}
+	lanRanges      = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16", "fc00::/7", "fe80::/10"}
+	anyRanges      = []string{"0.0.0.0/0", "::/0"}
+)
+
+// ParseAllowList parses a comma-separated list of client address ranges.
+// Besides CIDRs and single IPs it accepts "loopback", "lan" (private ranges
+// plus loopback) and "any" to lift a global restriction on a single route.
+func ParseAllowList(value string) ([]string, error) {
+	ranges := []string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		switch entry {
+		case "":
+			continue
+		case "loopback":
+			ranges = append(ranges, loopbackRanges...)
+		case "lan":
+			ranges = append(ranges, loopbackRanges...)
+			ranges = append(ranges, lanRanges...)
+		case "any":
+			ranges = append(ranges, anyRanges...)
+		default:
+			if prefix, err := netip.ParsePrefix(entry); err == nil {
+				ranges = append(ranges, prefix.String())
+			} else if addr, err := netip.ParseAddr(entry); err == nil {
+				ranges = append(ranges, netip.PrefixFrom(addr, addr.BitLen()).String())
+			} else {
+				return nil, fmt.Errorf("invalid address range %q", entry)
+			}
+		}
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseAllowList(t *testing.T) {
+	tests := []struct {
+		value   string
+		ranges  []string
+		wantErr bool
+	}{
+		{value: "", ranges: []string{}},
+		{value: "10.0.0.0/8", ranges: []string{"10.0.0.0/8"}},
+		{value: "192.168.1.5, ::1", ranges: []string{"192.168.1.5/32", "::1/128"}},
+		{value: "fd00::/8,,", ranges: []string{"fd00::/8"}},
+		{value: "loopback", ranges: loopbackRanges},
+		{value: "lan", ranges: append(slices.Clone(loopbackRanges), lanRanges...)},
+		{value: "any", ranges: anyRanges},
+		{value: "localhost", wantErr: true},
+		{value: "10.0.0.0/33", wantErr: true},
+		{value: "loopback,300.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ranges, err := ParseAllowList(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseAllowList(%q) = %v, want an error", tt.value, ranges)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAllowList(%q): %v", tt.value, err)
+			}
+			if !slices.Equal(ranges, tt.ranges) {
+				t.Errorf("ParseAllowList(%q) = %v, want %v", tt.value, ranges, tt.ranges)
+			}
+		})
+	}
+}
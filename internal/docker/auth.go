@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BasicAuthUser is an account accepted by a protected route. Password holds
+// a bcrypt hash, as produced by `caddy hash-password` or `htpasswd -nbB`.
+type BasicAuthUser struct {
+	Username string
+	Password string
+}
+
+// BasicAuth protects a route with HTTP basic authentication. A route with no
+// valid users rejects every request rather than being left open.
+type BasicAuth struct {
+	Users []BasicAuthUser
+}
+
+// ParseBasicAuthUsers parses htpasswd-style "user:hash" entries separated by
+// commas or newlines. Blank lines and lines starting with # are ignored.
+func ParseBasicAuthUsers(value string) ([]BasicAuthUser, error) {
+	var users []BasicAuthUser
+
+	entries := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		username, hash, found := strings.Cut(entry, ":")
+		if !found || username == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected user:hash", entry)
+		}
+		if !isBcryptHash(hash) {
+			return nil, fmt.Errorf("password for %q is not a bcrypt hash", username)
+		}
+
+		users = append(users, BasicAuthUser{
+			Username: username,
+			Password: hash,
+		})
+	}
+
+	return users, nil
+}
+
+// LoadBasicAuthFile reads users from an htpasswd-style file
+func LoadBasicAuthFile(path string) ([]BasicAuthUser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBasicAuthUsers(string(data))
+}
+
+func isBcryptHash(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForwardAuth delegates request authorization to an external service, such
+// as oauth2-proxy or Authelia. Either Dial is known up front (URL or host
+// address) or Service names a compose service resolved by the manager.
+type ForwardAuth struct {
+	Project     string
+	Service     string
+	Port        int
+	Dial        string
+	URI         string
+	TLS         bool
+	CopyHeaders []string
+}
+
+// ParseForwardAuth parses a devproxy.forward_auth value: either a URL such as
+// http://auth.internal:4180/oauth2/auth, or a compose service name with an
+// optional port and path, such as authelia:9091/api/verify. Service names
+// are looked up within project; without a project they are used as hosts.
+func ParseForwardAuth(value, project string) (*ForwardAuth, error) {
+	auth := &ForwardAuth{URI: "/"}
+
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("missing host in %q", value)
+		}
+
+		port := u.Port()
+		switch u.Scheme {
+		case "https":
+			auth.TLS = true
+			if port == "" {
+				port = "443"
+			}
+		case "http":
+			if port == "" {
+				port = "80"
+			}
+		default:
+			return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+
+		auth.Dial = net.JoinHostPort(u.Hostname(), port)
+		if u.RequestURI() != "" {
+			auth.URI = u.RequestURI()
+		}
+		return auth, nil
+	}
+
+	hostPort, uri, hasPath := strings.Cut(value, "/")
+	if hasPath {
+		auth.URI = "/" + uri
+	}
+
+	host, portStr, hasPort := strings.Cut(hostPort, ":")
+	if host == "" {
+		return nil, fmt.Errorf("missing service in %q", value)
+	}
+	if hasPort {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in %q", value)
+		}
+		auth.Port = port
+	}
+
+	if project == "" {
+		if auth.Port == 0 {
+			auth.Port = 80
+		}
+		auth.Dial = net.JoinHostPort(host, strconv.Itoa(auth.Port))
+		return auth, nil
+	}
+
+	auth.Project = project
+	auth.Service = host
+	return auth, nil
+}
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseBasicAuthUsers(t *testing.T) {
+	const hash = "$2a$14$Zkx19XLiW6VYouLHR5NmfOFU0z2GTNmpkT/5qqR7hx4IjWJPDhjvG"
+
+	tests := []struct {
+		name    string
+		value   string
+		users   []string
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{name: "single", value: "alice:" + hash, users: []string{"alice"}},
+		{name: "comma separated", value: "alice:" + hash + ", bob:$2y$10$abc", users: []string{"alice", "bob"}},
+		{name: "htpasswd file", value: "# users\nalice:" + hash + "\n\n  bob:$2b$10$abc  \n", users: []string{"alice", "bob"}},
+		{name: "missing colon", value: "alice", wantErr: true},
+		{name: "missing user", value: ":" + hash, wantErr: true},
+		{name: "plain password", value: "alice:secret", wantErr: true},
+		{name: "md5 hash", value: "alice:$apr1$abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := ParseBasicAuthUsers(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseBasicAuthUsers(%q) = %v, want an error", tt.value, users)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBasicAuthUsers(%q): %v", tt.value, err)
+			}
+
+			var names []string
+			for _, user := range users {
+				if !isBcryptHash(user.Password) {
+					t.Errorf("password of %s = %q, want the bcrypt hash", user.Username, user.Password)
+				}
+				names = append(names, user.Username)
+			}
+			if !slices.Equal(names, tt.users) {
+				t.Errorf("users = %v, want %v", names, tt.users)
+			}
+		})
+	}
+}
+
+func TestParseForwardAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		project string
+		want    ForwardAuth
+		wantErr bool
+	}{
+		{
+			name:  "http url",
+			value: "http://auth.internal:4180/oauth2/auth",
+			want:  ForwardAuth{Dial: "auth.internal:4180", URI: "/oauth2/auth"},
+		},
+		{
+			name:  "https url without port",
+			value: "https://auth.example.com/verify?rd=1",
+			want:  ForwardAuth{Dial: "auth.example.com:443", URI: "/verify?rd=1", TLS: true},
+		},
+		{
+			name:  "ipv6 url",
+			value: "http://[fd00::5]/",
+			want:  ForwardAuth{Dial: "[fd00::5]:80", URI: "/"},
+		},
+		{
+			name:    "compose service",
+			value:   "authelia:9091/api/verify",
+			project: "shop",
+			want:    ForwardAuth{Project: "shop", Service: "authelia", Port: 9091, URI: "/api/verify"},
+		},
+		{
+			name:    "compose service without port",
+			value:   "oauth2-proxy",
+			project: "shop",
+			want:    ForwardAuth{Project: "shop", Service: "oauth2-proxy", URI: "/"},
+		},
+		{
+			name:  "host without project",
+			value: "auth.internal/check",
+			want:  ForwardAuth{Port: 80, Dial: "auth.internal:80", URI: "/check"},
+		},
+		{name: "unsupported scheme", value: "ftp://auth.internal", wantErr: true},
+		{name: "url without host", value: "http:///verify", wantErr: true},
+		{name: "missing service", value: ":9091/verify", project: "shop", wantErr: true},
+		{name: "invalid port", value: "authelia:http", project: "shop", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := ParseForwardAuth(tt.value, tt.project)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseForwardAuth(%q) = %+v, want an error", tt.value, auth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseForwardAuth(%q): %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(*auth, tt.want) {
+				t.Errorf("ParseForwardAuth(%q) = %+v, want %+v", tt.value, *auth, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// CrashReport describes how a container exited abnormally
+type CrashReport struct {
+	OOMKilled    bool
+	RestartCount int
+	Error        string
+	Logs         string // last lines of output, stdout and stderr interleaved
+}
+
+// Crashed reports whether a container is stopped after a non-zero exit or
+// an out of memory kill
+func Crashed(container types.ContainerJSON) bool {
+	if container.State == nil || container.State.Running {
+		return false
+	}
+	return container.State.ExitCode != 0 || container.State.OOMKilled
+}
+
+// signalNames names the signals Docker reports by number in kill events
+var signalNames = map[string]string{"2": "INT", "3": "QUIT", "9": "KILL", "15": "TERM"}
+
+// StopSignal reports whether a kill event's signal stops the container, as
+// docker stop and restart send, rather than a signal it handles such as
+// HUP. Unknown signals count as stopping.
+func StopSignal(info types.ContainerJSON, signal string) bool {
+	name := normalizeSignal(signal)
+	switch name {
+	case "", "INT", "QUIT", "KILL", "TERM":
+		return true
+	}
+	return info.Config != nil && info.Config.StopSignal != "" && normalizeSignal(info.Config.StopSignal) == name
+}
+
+func normalizeSignal(signal string) string {
+	if name, found := signalNames[signal]; found {
+		return name
+	}
+	return strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+}
+
+// NewCrashReport collects the exit details of a container, with logs
+// fetched separately through Monitor.TailLogs
+func NewCrashReport(container types.ContainerJSON, logs string) *CrashReport {
+	return &CrashReport{
+		OOMKilled:    container.State.OOMKilled,
+		RestartCount: container.RestartCount,
+		Error:        container.State.Error,
+		Logs:         logs,
+	}
+}
+
+// TailLogs returns the last lines of a container's output
+func (m *Monitor) TailLogs(ctx context.Context, info types.ContainerJSON, lines int) (string, error) {
+	reader, err := m.client.ContainerLogs(ctx, info.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+
+	// Without a TTY, Docker multiplexes stdout and stderr in one stream
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(&buf, reader)
+	} else {
+		_, err = stdcopy.StdCopy(&buf, &buf, reader)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
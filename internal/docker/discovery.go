@@ -2,27 +2,204 @@ package docker
 
 import (
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 )
 
+// TLS modes selected with the devproxy.tls label
+const (
+	TLSModeBoth      = "both"       // serve over HTTP and HTTPS
+	TLSModeRedirect  = "redirect"   // redirect HTTP to HTTPS
+	TLSModeHTTPSOnly = "https-only" // only serve over HTTPS
+	TLSModeHTTPOnly  = "http-only"  // only serve over plain HTTP
+)
+
+// Upstream schemes selected with the devproxy.scheme label
+const (
+	SchemeHTTP  = "http"  // plain HTTP/1.1
+	SchemeHTTPS = "https" // HTTP over TLS
+	SchemeH2C   = "h2c"   // cleartext HTTP/2, e.g. for gRPC
+)
+
+// SourceDocker tags targets discovered from Docker containers
+const SourceDocker = "docker"
+
+// UpstreamTLS configures how the proxy verifies HTTPS upstreams
+type UpstreamTLS struct {
+	ServerName         string
+	InsecureSkipVerify bool
+	CAFile             string
+}
+
+// HeaderOps lists header changes applied to requests or responses
+type HeaderOps struct {
+	Set    map[string]string
+	Add    map[string]string
+	Delete []string
+}
+
 type ProxyTarget struct {
-	Domain      string
-	ContainerIP string
-	Port        int
-	IsSecure    bool
+	// Where the target comes from, SourceDocker for containers
+	Source string
+
+	// Docker host of the container, empty for targets from other sources
+	Endpoint string
+
+	Domain         string
+	Path           string
+	ContainerID    string
+	ContainerName  string
+	ContainerState string
+	ContainerIP    string // or the host name the target is dialed at
+	DualStackIP    string // address of the other IP family, dialed too
+	Port           int
+	Priority       int
+	TLSMode        string
+	Scheme         string
+	UpstreamTLS    *UpstreamTLS
+
+	RequestHeaders  HeaderOps
+	ResponseHeaders HeaderOps
+
+	BasicAuth   *BasicAuth
+	ForwardAuth *ForwardAuth
+
+	// Client address ranges allowed to reach the route; nil means no
+	// restriction of its own, empty means nobody
+	Allow []string
+
+	// Request limits and upstream timeouts; zero values keep Caddy's defaults
+	MaxBodySize           int64
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	ReadTimeout           time.Duration
+	WriteTimeout          time.Duration
+	FlushInterval         time.Duration
+	TryDuration           time.Duration
+	TryInterval           time.Duration
+
+	HealthCheck *HealthCheck
+
+	// Set for routes kept while their container is not running; they serve
+	// a page instead of proxying
+	Stopped   bool
+	StoppedAt time.Time
+	ExitCode  int
+	Crash     *CrashReport
+
+	// Start the stopped container on the first request to its route
+	Autostart bool
+
+	// Stop the container after this long without requests; zero never stops it
+	IdleTimeout time.Duration
 }
 
-type Discovery struct{}
+// Dial returns the address Caddy dials to reach the target
+func (t ProxyTarget) Dial() string {
+	return net.JoinHostPort(t.ContainerIP, strconv.Itoa(t.Port))
+}
 
-func NewDiscovery() *Discovery {
-	return &Discovery{}
+// Dials returns every address Caddy dials to reach the target, both
+// families of dual-stack containers
+func (t ProxyTarget) Dials() []string {
+	dials := []string{t.Dial()}
+	if t.DualStackIP != "" {
+		dials = append(dials, net.JoinHostPort(t.DualStackIP, strconv.Itoa(t.Port)))
+	}
+	return dials
+}
+
+type Discovery struct {
+	endpoint Endpoint
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	warned map[string]bool // container ID and message -> logged
+}
+
+// NewDiscovery creates the discovery of containers of the given Docker host
+func NewDiscovery(endpoint Endpoint, logger *slog.Logger) *Discovery {
+	return &Discovery{
+		endpoint: endpoint,
+		logger:   logger,
+		warned:   make(map[string]bool),
+	}
+}
+
+// warnOnce logs a warning about a container the first time only. Targets
+// are extracted again on every refresh, which would repeat it.
+func (d *Discovery) warnOnce(container types.ContainerJSON, msg string, args ...any) {
+	key := container.ID + "\x00" + msg
+
+	d.mu.Lock()
+	warned := d.warned[key]
+	d.warned[key] = true
+	d.mu.Unlock()
+
+	if !warned {
+		d.logger.Warn(msg, args...)
+	}
 }
 
 func (d *Discovery) ExtractProxyTargets(container types.ContainerJSON) []ProxyTarget {
+	// Skip if container is not running
+	if !container.State.Running {
+		return nil
+	}
+
+	if d.endpoint.Upstream == UpstreamPublished {
+		return d.extractPublishedTargets(container)
+	}
+
+	containerIP := d.extractUpstreamHost(container)
+	if containerIP == "" {
+		return nil
+	}
+
+	targets := d.extractTargets(container, containerIP)
+
+	// Dual-stack containers are dialed over both families
+	if primary, secondary := d.extractContainerAddresses(container); secondary != "" && primary == containerIP {
+		for i := range targets {
+			targets[i].DualStackIP = secondary
+		}
+	}
+
+	return targets
+}
+
+// ExtractStoppedTargets returns the routes a container that is not running
+// would have, marked as stopped. Stopped containers have no address, so the
+// targets cannot be proxied to.
+func (d *Discovery) ExtractStoppedTargets(container types.ContainerJSON) []ProxyTarget {
+	if container.State.Running || container.State.Restarting {
+		return nil
+	}
+
+	targets := d.extractTargets(container, "")
+
+	// Containers that never ran report the zero time as their finish time
+	stoppedAt, _ := time.Parse(time.RFC3339Nano, container.State.FinishedAt)
+	for i := range targets {
+		targets[i].Stopped = true
+		targets[i].StoppedAt = stoppedAt
+		targets[i].ExitCode = container.State.ExitCode
+	}
+
+	return targets
+}
+
+func (d *Discovery) extractTargets(container types.ContainerJSON, containerIP string) []ProxyTarget {
 	var targets []ProxyTarget
 
 	if !d.shouldProxy(container) {
@@ -30,31 +207,59 @@ func (d *Discovery) ExtractProxyTargets(container types.ContainerJSON) []ProxyTa
 	}
 
 	domains := d.extractDomains(container)
-	containerIP := d.extractContainerIP(container)
 	port := d.extractPort(container)
 
-	if port == 0 || containerIP == "" {
+	if port == 0 {
 		return targets
 	}
 
+	// Labels apply to every domain of the container
+	base := ProxyTarget{
+		Source:         SourceDocker,
+		Endpoint:       d.endpoint.Name,
+		Path:           d.extractPath(container),
+		ContainerID:    container.ID,
+		ContainerName:  strings.TrimPrefix(container.Name, "/"),
+		ContainerState: container.State.Status,
+		ContainerIP:    containerIP,
+		Port:           port,
+		Priority:       d.extractPriority(container),
+		TLSMode:        d.extractTLSMode(container),
+
+		RequestHeaders:  d.extractHeaderOps(container, "devproxy.headers.request."),
+		ResponseHeaders: d.extractHeaderOps(container, "devproxy.headers.response."),
+
+		BasicAuth:   d.extractBasicAuth(container),
+		ForwardAuth: d.extractForwardAuth(container),
+
+		Allow: d.extractAllow(container),
+
+		MaxBodySize:           d.extractSize(container, "devproxy.max_body_size"),
+		DialTimeout:           d.extractDuration(container, "devproxy.timeout.dial"),
+		ResponseHeaderTimeout: d.extractDuration(container, "devproxy.timeout.response_header"),
+		ReadTimeout:           d.extractDuration(container, "devproxy.timeout.read"),
+		WriteTimeout:          d.extractDuration(container, "devproxy.timeout.write"),
+		FlushInterval:         d.extractFlushInterval(container),
+		TryDuration:           d.extractDuration(container, "devproxy.retry.duration"),
+		TryInterval:           d.extractDuration(container, "devproxy.retry.interval"),
+
+		HealthCheck: d.extractHealthCheck(container, port),
+
+		Autostart:   d.extractBool(container, "devproxy.autostart"),
+		IdleTimeout: d.extractDuration(container, "devproxy.idle_timeout"),
+	}
+	base.Scheme, base.UpstreamTLS = d.extractScheme(container)
+
 	for _, domain := range domains {
-		targets = append(targets, ProxyTarget{
-			Domain:      domain,
-			ContainerIP: containerIP,
-			Port:        port,
-			IsSecure:    true, // Always use HTTPS
-		})
+		target := base
+		target.Domain = d.endpointDomain(domain)
+		targets = append(targets, target)
 	}
 
 	return targets
 }
 
 func (d *Discovery) shouldProxy(container types.ContainerJSON) bool {
-	// Skip if container is not running
-	if !container.State.Running {
-		return false
-	}
-
 	// Skip if explicitly disabled
 	if val, exists := container.Config.Labels["devproxy.enabled"]; exists && val == "false" {
 		return false
@@ -101,20 +306,24 @@ func (d *Discovery) extractDomains(container types.ContainerJSON) []string {
 	return domains
 }
 
-func (d *Discovery) extractContainerIP(container types.ContainerJSON) string {
-	// First try to get IP from custom networks
-	for networkName, network := range container.NetworkSettings.Networks {
-		if networkName != "bridge" && network.IPAddress != "" {
-			return network.IPAddress
-		}
+// endpointDomain adds the endpoint's prefix and suffix around the first
+// label of a domain, keeping containers of different hosts apart
+func (d *Discovery) endpointDomain(domain string) string {
+	if d.endpoint.DomainPrefix == "" && d.endpoint.DomainSuffix == "" {
+		return domain
 	}
 
-	// Fallback to default bridge network
-	if container.NetworkSettings.DefaultNetworkSettings.IPAddress != "" {
-		return container.NetworkSettings.DefaultNetworkSettings.IPAddress
+	first, rest, _ := strings.Cut(domain, ".")
+	domain = d.endpoint.DomainPrefix + first + d.endpoint.DomainSuffix
+	if rest != "" {
+		domain += "." + rest
 	}
+	return domain
+}
 
-	return ""
+func (d *Discovery) extractContainerIP(container types.ContainerJSON) string {
+	containerIP, _ := d.extractContainerAddresses(container)
+	return containerIP
 }
 
 func (d *Discovery) extractPort(container types.ContainerJSON) int {
@@ -153,6 +362,238 @@ func (d *Discovery) extractPort(container types.ContainerJSON) int {
 	return 0
 }
 
+func (d *Discovery) extractPath(container types.ContainerJSON) string {
+	// Routes without a path label match every path of their domain
+	path := strings.TrimRight(container.Config.Labels["devproxy.path"], "/")
+	if path == "" {
+		return ""
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+func (d *Discovery) extractPriority(container types.ContainerJSON) int {
+	if customPriority, exists := container.Config.Labels["devproxy.priority"]; exists {
+		if priority, err := strconv.Atoi(customPriority); err == nil {
+			return priority
+		}
+	}
+	return 0
+}
+
+func (d *Discovery) extractTLSMode(container types.ContainerJSON) string {
+	switch mode := container.Config.Labels["devproxy.tls"]; mode {
+	case TLSModeRedirect, TLSModeHTTPSOnly, TLSModeHTTPOnly:
+		return mode
+	default:
+		// Unset or unknown values keep the historical behavior
+		return TLSModeBoth
+	}
+}
+
+func (d *Discovery) extractScheme(container types.ContainerJSON) (string, *UpstreamTLS) {
+	labels := container.Config.Labels
+
+	switch scheme := labels["devproxy.scheme"]; scheme {
+	case SchemeH2C:
+		return scheme, nil
+	case SchemeHTTPS:
+		insecure, _ := strconv.ParseBool(labels["devproxy.upstream.tls.insecure_skip_verify"])
+		return scheme, &UpstreamTLS{
+			ServerName:         labels["devproxy.upstream.tls.server_name"],
+			InsecureSkipVerify: insecure,
+			CAFile:             labels["devproxy.upstream.tls.ca"],
+		}
+	default:
+		return SchemeHTTP, nil
+	}
+}
+
+// extractHeaderOps reads <prefix>set.<Name>, <prefix>add.<Name> and the
+// comma-separated <prefix>delete labels
+func (d *Discovery) extractHeaderOps(container types.ContainerJSON, prefix string) HeaderOps {
+	var ops HeaderOps
+
+	for key, value := range container.Config.Labels {
+		op, found := strings.CutPrefix(key, prefix)
+		if !found {
+			continue
+		}
+
+		if name, found := strings.CutPrefix(op, "set."); found && name != "" {
+			if ops.Set == nil {
+				ops.Set = make(map[string]string)
+			}
+			ops.Set[http.CanonicalHeaderKey(name)] = value
+		} else if name, found := strings.CutPrefix(op, "add."); found && name != "" {
+			if ops.Add == nil {
+				ops.Add = make(map[string]string)
+			}
+			ops.Add[http.CanonicalHeaderKey(name)] = value
+		} else if op == "delete" {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					ops.Delete = append(ops.Delete, http.CanonicalHeaderKey(name))
+				}
+			}
+		}
+	}
+
+	// Label maps have no order; keep the output stable
+	sort.Strings(ops.Delete)
+	return ops
+}
+
+func (d *Discovery) extractBasicAuth(container types.ContainerJSON) *BasicAuth {
+	labels := container.Config.Labels
+	inlineUsers, hasUsers := labels["devproxy.auth.basic.users"]
+	usersFile, hasFile := labels["devproxy.auth.basic.users_file"]
+	if !hasUsers && !hasFile {
+		return nil
+	}
+
+	// Invalid credentials leave the route locked rather than open
+	auth := &BasicAuth{}
+
+	if hasUsers {
+		users, err := ParseBasicAuthUsers(inlineUsers)
+		if err != nil {
+			d.logger.Warn("Invalid basic auth users label, route will reject all requests",
+				"container", container.Name, "error", err)
+			return auth
+		}
+		auth.Users = append(auth.Users, users...)
+	}
+
+	if hasFile {
+		users, err := LoadBasicAuthFile(usersFile)
+		if err != nil {
+			d.logger.Warn("Failed to load basic auth users file, route will reject all requests",
+				"container", container.Name, "file", usersFile, "error", err)
+			return &BasicAuth{}
+		}
+		auth.Users = append(auth.Users, users...)
+	}
+
+	return auth
+}
+
+func (d *Discovery) extractForwardAuth(container types.ContainerJSON) *ForwardAuth {
+	labels := container.Config.Labels
+	value, exists := labels["devproxy.forward_auth"]
+	if !exists {
+		return nil
+	}
+
+	auth, err := ParseForwardAuth(value, labels["com.docker.compose.project"])
+	if err != nil {
+		// An empty dial address makes the route reject requests
+		d.logger.Warn("Invalid forward auth label, route will reject all requests",
+			"container", container.Name, "error", err)
+		return &ForwardAuth{}
+	}
+
+	if uri, exists := labels["devproxy.forward_auth.uri"]; exists {
+		auth.URI = uri
+	}
+	for _, name := range strings.Split(labels["devproxy.forward_auth.copy_headers"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			auth.CopyHeaders = append(auth.CopyHeaders, http.CanonicalHeaderKey(name))
+		}
+	}
+
+	return auth
+}
+
+func (d *Discovery) extractAllow(container types.ContainerJSON) []string {
+	value, exists := container.Config.Labels["devproxy.allow"]
+	if !exists {
+		return nil
+	}
+
+	ranges, err := ParseAllowList(value)
+	if err != nil {
+		d.logger.Warn("Invalid allow label, route will reject all requests",
+			"container", container.Name, "error", err)
+		return []string{}
+	}
+	return ranges
+}
+
+func (d *Discovery) extractBool(container types.ContainerJSON, label string) bool {
+	value, exists := container.Config.Labels[label]
+	if !exists {
+		return false
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		d.logger.Warn("Ignoring invalid boolean label", "container", container.Name, "label", label, "value", value)
+		return false
+	}
+	return enabled
+}
+
+func (d *Discovery) extractDuration(container types.ContainerJSON, label string) time.Duration {
+	value, exists := container.Config.Labels[label]
+	if !exists {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		d.logger.Warn("Ignoring invalid duration label", "container", container.Name, "label", label, "value", value)
+		return 0
+	}
+	return duration
+}
+
+func (d *Discovery) extractFlushInterval(container types.ContainerJSON) time.Duration {
+	// -1 flushes immediately, as needed by server-sent events
+	if container.Config.Labels["devproxy.flush_interval"] == "-1" {
+		return -1
+	}
+	return d.extractDuration(container, "devproxy.flush_interval")
+}
+
+func (d *Discovery) extractSize(container types.ContainerJSON, label string) int64 {
+	value, exists := container.Config.Labels[label]
+	if !exists {
+		return 0
+	}
+
+	size, err := units.RAMInBytes(value)
+	if err != nil || size <= 0 {
+		d.logger.Warn("Ignoring invalid size label", "container", container.Name, "label", label, "value", value)
+		return 0
+	}
+	return size
+}
+
+// ServiceDial returns the address used to reach a container, honoring port
+// when non-zero and falling back to the container's detected port.
+func (d *Discovery) ServiceDial(container types.ContainerJSON, port int) string {
+	if port == 0 {
+		port = d.extractPort(container)
+	}
+
+	if d.endpoint.Upstream == UpstreamPublished {
+		hostPort := publishedPort(container, port)
+		if hostPort == 0 {
+			return ""
+		}
+		return net.JoinHostPort(d.endpoint.publishedHost(), strconv.Itoa(hostPort))
+	}
+
+	containerIP := d.extractUpstreamHost(container)
+	if containerIP == "" {
+		return ""
+	}
+	return net.JoinHostPort(containerIP, strconv.Itoa(port))
+}
+
 func (d *Discovery) GetContainerKey(container types.ContainerJSON) string {
 	return container.ID
 }
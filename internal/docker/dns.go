@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// extractDNSName returns the name Caddy reaches a container by through
+// Docker's embedded DNS, which always resolves to the container's current
+// address. That is the container's name, or the alias set with
+// devproxy.dns_name, on the first of the endpoint's networks it is attached
+// to. It is empty when the container shares none of them with Caddy.
+func (d *Discovery) extractDNSName(container types.ContainerJSON) string {
+	if container.NetworkSettings == nil {
+		return ""
+	}
+
+	name := strings.TrimPrefix(container.Name, "/")
+	for _, networkName := range d.endpoint.Networks {
+		network, attached := container.NetworkSettings.Networks[networkName]
+		if !attached || network == nil {
+			continue
+		}
+
+		alias := container.Config.Labels["devproxy.dns_name"]
+		if alias == "" {
+			return name
+		}
+		if slices.Contains(network.Aliases, alias) || slices.Contains(network.DNSNames, alias) {
+			return alias
+		}
+
+		d.warnOnce(container, "Container has no such DNS name on the shared network, using its name",
+			"container", name,
+			"network", networkName,
+			"dns_name", alias)
+		return name
+	}
+
+	return ""
+}
+
+// extractUpstreamHost returns the host Caddy dials a running container at,
+// its DNS name in the dns mode when it shares a network with Caddy and its
+// IP address otherwise
+func (d *Discovery) extractUpstreamHost(container types.ContainerJSON) string {
+	if d.endpoint.Upstream == UpstreamDNS {
+		if name := d.extractDNSName(container); name != "" {
+			return name
+		}
+		d.logger.Debug("Container shares no network with Caddy, dialing its IP address",
+			"container", strings.TrimPrefix(container.Name, "/"),
+			"networks", d.endpoint.Networks)
+	}
+
+	return d.extractContainerIP(container)
+}
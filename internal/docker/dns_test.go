@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestExtractDNSName(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks map[string]*network.EndpointSettings
+		labels   map[string]string
+		want     string
+	}{
+		{
+			name:     "container name on a shared network",
+			networks: map[string]*network.EndpointSettings{"devproxy": {}},
+			want:     "shop-web-1",
+		},
+		{
+			name:     "no shared network",
+			networks: map[string]*network.EndpointSettings{"shop_default": {}},
+		},
+		{
+			name: "alias on the first shared network",
+			networks: map[string]*network.EndpointSettings{
+				"devproxy": {Aliases: []string{"web"}},
+				"shared":   {Aliases: []string{"other"}},
+			},
+			labels: map[string]string{"devproxy.dns_name": "web"},
+			want:   "web",
+		},
+		{
+			name:     "alias from the network's DNS names",
+			networks: map[string]*network.EndpointSettings{"shared": {DNSNames: []string{"shop-web-1", "web"}}},
+			labels:   map[string]string{"devproxy.dns_name": "web"},
+			want:     "web",
+		},
+		{
+			name:     "unknown alias falls back to the name",
+			networks: map[string]*network.EndpointSettings{"devproxy": {Aliases: []string{"api"}}},
+			labels:   map[string]string{"devproxy.dns_name": "web"},
+			want:     "shop-web-1",
+		},
+	}
+
+	d := NewDiscovery(Endpoint{Networks: []string{"devproxy", "shared"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{Name: "/shop-web-1"},
+				Config:            &container.Config{Labels: tt.labels},
+				NetworkSettings:   &types.NetworkSettings{Networks: tt.networks},
+			}
+
+			if got := d.extractDNSName(container); got != tt.want {
+				t.Errorf("extractDNSName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// DefaultEndpoint is the name of the Docker host configured through the
+// standard DOCKER_* environment variables
+const DefaultEndpoint = "local"
+
+// Upstream addressing modes of an endpoint
+const (
+	UpstreamIP        = "ip"        // dial the container's IP address
+	UpstreamPublished = "published" // dial the port published on the Docker host
+	UpstreamDNS       = "dns"       // dial the container's name on a network shared with Caddy
+)
+
+// EndpointsFile lists the Docker hosts devproxy watches
+type EndpointsFile struct {
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Endpoint is a Docker host, given as a daemon address, a Docker context or
+// neither to use the DOCKER_* environment variables
+type Endpoint struct {
+	Name    string       `json:"name"`
+	Host    string       `json:"host,omitempty"`
+	TLS     *EndpointTLS `json:"tls,omitempty"`
+	Context string       `json:"context,omitempty"`
+
+	// Added around the first label of the domains of the host's
+	// containers, e.g. "vm-" turns app.localhost into vm-app.localhost
+	DomainPrefix string `json:"domain_prefix,omitempty"`
+	DomainSuffix string `json:"domain_suffix,omitempty"`
+
+	// How Caddy reaches containers, UpstreamIP, UpstreamPublished or
+	// UpstreamDNS. With published ports, PublishedHost is the address
+	// dialed, defaulting to the daemon's host for TCP endpoints and the
+	// global published host otherwise.
+	Upstream      string `json:"upstream,omitempty"`
+	PublishedHost string `json:"published_host,omitempty"`
+
+	// Docker networks Caddy is attached to, where UpstreamDNS resolves
+	// container names; containers on none of them are dialed by IP
+	Networks []string `json:"networks,omitempty"`
+
+	// IP family containers are dialed over by network name, IPv4, IPv6 or
+	// IPEither, with AnyNetwork for networks not listed
+	IPFamily map[string]string `json:"ip_family,omitempty"`
+
+	// Published host of endpoints that do not set one, from the defaults
+	defaultPublishedHost string
+}
+
+// EndpointTLS holds the certificate files of a TLS-protected daemon
+type EndpointTLS struct {
+	CA   string `json:"ca"`
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// LoadEndpoints reads the endpoints file at path. Without a path, the only
+// endpoint is the default one. The upstream mode, published host, networks
+// and IP families of defaults apply to endpoints that do not set their own.
+func LoadEndpoints(path string, defaults Endpoint) ([]Endpoint, error) {
+	endpoints := []Endpoint{{Name: DefaultEndpoint}}
+	if path != "" {
+		var err error
+		if endpoints, err = readEndpoints(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		if endpoint.Upstream == "" {
+			endpoint.Upstream = defaults.Upstream
+		}
+		endpoint.defaultPublishedHost = defaults.PublishedHost
+		if len(endpoint.Networks) == 0 {
+			endpoint.Networks = defaults.Networks
+		}
+		if endpoint.IPFamily == nil {
+			endpoint.IPFamily = defaults.IPFamily
+		}
+		if err := checkIPFamilies(endpoint.IPFamily); err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+		}
+
+		switch endpoint.Upstream {
+		case "", UpstreamIP, UpstreamPublished, UpstreamDNS:
+		default:
+			return nil, fmt.Errorf("endpoint %s: unknown upstream mode %q, expected %q, %q or %q", endpoint.Name, endpoint.Upstream, UpstreamIP, UpstreamPublished, UpstreamDNS)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// readEndpoints reads and checks the endpoints file at path
+func readEndpoints(path string) ([]Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file EndpointsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(file.Endpoints) == 0 {
+		return nil, fmt.Errorf("%s lists no endpoints", path)
+	}
+
+	seen := make(map[string]bool)
+	for i, endpoint := range file.Endpoints {
+		if endpoint.Name == "" {
+			return nil, fmt.Errorf("endpoint %d: missing name", i+1)
+		}
+		if seen[endpoint.Name] {
+			return nil, fmt.Errorf("endpoint %s: duplicate name", endpoint.Name)
+		}
+		seen[endpoint.Name] = true
+
+		if endpoint.Host != "" && endpoint.Context != "" {
+			return nil, fmt.Errorf("endpoint %s: host and context are exclusive", endpoint.Name)
+		}
+	}
+
+	return file.Endpoints, nil
+}
+
+// clientOptions returns the Docker client options reaching the endpoint
+func (e Endpoint) clientOptions() ([]client.Opt, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+
+	host, tls := e.Host, e.TLS
+	if e.Context != "" {
+		var err error
+		host, tls, err = resolveContext(e.Context)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", e.Name, err)
+		}
+	}
+
+	if host == "" {
+		return append(opts, client.FromEnv), nil
+	}
+
+	opts = append(opts, client.WithHost(host))
+	if tls != nil {
+		opts = append(opts, client.WithTLSClientConfig(tls.CA, tls.Cert, tls.Key))
+	}
+	return opts, nil
+}
+
+// publishedHost returns the address published ports are dialed at
+func (e Endpoint) publishedHost() string {
+	if e.PublishedHost != "" {
+		return e.PublishedHost
+	}
+
+	// Ports of a remote daemon are published on its own host
+	daemon := e.Host
+	if e.Context != "" {
+		daemon, _, _ = resolveContext(e.Context)
+	}
+	if u, err := url.Parse(daemon); err == nil && u.Scheme == "tcp" {
+		if host, _, err := net.SplitHostPort(u.Host); err == nil {
+			return host
+		}
+	}
+
+	if e.defaultPublishedHost != "" {
+		return e.defaultPublishedHost
+	}
+	return "host.docker.internal"
+}
+
+// isLoopback reports whether host names the local machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// contextMeta is the part of a Docker context's meta.json devproxy uses
+type contextMeta struct {
+	Endpoints map[string]struct {
+		Host string `json:"Host"`
+	} `json:"Endpoints"`
+}
+
+// resolveContext reads the daemon address and TLS files of a Docker CLI
+// context from the Docker config directory
+func resolveContext(name string) (string, *EndpointTLS, error) {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil, err
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+
+	// Context directories are named after the digest of the context name
+	digest := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(digest[:])
+
+	data, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", id, "meta.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, fmt.Errorf("unknown docker context %q", name)
+		}
+		return "", nil, err
+	}
+
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", nil, fmt.Errorf("invalid docker context %q: %w", name, err)
+	}
+	endpoint, found := meta.Endpoints["docker"]
+	if !found || endpoint.Host == "" {
+		return "", nil, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	if _, err := os.Stat(filepath.Join(tlsDir, "cert.pem")); err != nil || strings.HasPrefix(endpoint.Host, "unix://") {
+		return endpoint.Host, nil, nil
+	}
+
+	tls := &EndpointTLS{
+		Cert: filepath.Join(tlsDir, "cert.pem"),
+		Key:  filepath.Join(tlsDir, "key.pem"),
+	}
+	// Without a CA, the system roots verify the daemon
+	if _, err := os.Stat(filepath.Join(tlsDir, "ca.pem")); err == nil {
+		tls.CA = filepath.Join(tlsDir, "ca.pem")
+	}
+	return endpoint.Host, tls, nil
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLoadEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // endpoints file content, none when empty
+		names   []string
+		wantErr bool
+	}{
+		{name: "no file", names: []string{DefaultEndpoint}},
+		{
+			name:  "several hosts",
+			file:  `{"endpoints": [{"name": "local"}, {"name": "vm", "host": "tcp://10.0.0.5:2376", "domain_prefix": "vm-"}]}`,
+			names: []string{"local", "vm"},
+		},
+		{name: "invalid json", file: `{"endpoints": [`, wantErr: true},
+		{name: "no endpoints", file: `{"endpoints": []}`, wantErr: true},
+		{name: "missing name", file: `{"endpoints": [{"host": "tcp://10.0.0.5:2376"}]}`, wantErr: true},
+		{name: "duplicate name", file: `{"endpoints": [{"name": "vm"}, {"name": "vm"}]}`, wantErr: true},
+		{name: "host and context", file: `{"endpoints": [{"name": "vm", "host": "tcp://10.0.0.5:2376", "context": "vm"}]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), "endpoints.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			endpoints, err := LoadEndpoints(path, Endpoint{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadEndpoints() = %+v, want an error", endpoints)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadEndpoints(): %v", err)
+			}
+
+			var names []string
+			for _, endpoint := range endpoints {
+				names = append(names, endpoint.Name)
+			}
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("endpoints = %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestLoadEndpointsDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string // endpoints file content, none when empty
+		defaults Endpoint
+		want     []Endpoint
+		wantErr  bool
+	}{
+		{
+			name:     "default endpoint",
+			defaults: Endpoint{Upstream: UpstreamPublished, PublishedHost: "192.168.1.2"},
+			want:     []Endpoint{{Name: DefaultEndpoint, Upstream: UpstreamPublished, defaultPublishedHost: "192.168.1.2"}},
+		},
+		{
+			name:     "endpoints keep their own upstream mode",
+			file:     `{"endpoints": [{"name": "local"}, {"name": "vm", "upstream": "ip"}]}`,
+			defaults: Endpoint{Upstream: UpstreamPublished},
+			want: []Endpoint{
+				{Name: "local", Upstream: UpstreamPublished},
+				{Name: "vm", Upstream: UpstreamIP},
+			},
+		},
+		{
+			name:     "endpoints keep their own networks",
+			file:     `{"endpoints": [{"name": "local"}, {"name": "vm", "networks": ["vm_proxy"]}]}`,
+			defaults: Endpoint{Upstream: UpstreamDNS, Networks: []string{"devproxy"}},
+			want: []Endpoint{
+				{Name: "local", Upstream: UpstreamDNS, Networks: []string{"devproxy"}},
+				{Name: "vm", Upstream: UpstreamDNS, Networks: []string{"vm_proxy"}},
+			},
+		},
+		{
+			name:     "endpoints keep their own IP families",
+			file:     `{"endpoints": [{"name": "local"}, {"name": "vm", "ip_family": {"*": "v6"}}]}`,
+			defaults: Endpoint{IPFamily: map[string]string{"devproxy": IPv4}},
+			want: []Endpoint{
+				{Name: "local", IPFamily: map[string]string{"devproxy": IPv4}},
+				{Name: "vm", IPFamily: map[string]string{AnyNetwork: IPv6}},
+			},
+		},
+		{
+			name:    "unknown IP family",
+			file:    `{"endpoints": [{"name": "vm", "ip_family": {"devproxy": "v5"}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown upstream mode",
+			file:    `{"endpoints": [{"name": "vm", "upstream": "nat"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), "endpoints.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			endpoints, err := LoadEndpoints(path, tt.defaults)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadEndpoints() = %+v, want an error", endpoints)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadEndpoints(): %v", err)
+			}
+			if !reflect.DeepEqual(endpoints, tt.want) {
+				t.Errorf("LoadEndpoints() = %+v, want %+v", endpoints, tt.want)
+			}
+		})
+	}
+}
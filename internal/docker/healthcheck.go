@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Defaults applied when a container's Docker HEALTHCHECK leaves them unset
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 5 * time.Second
+)
+
+// HealthCheck configures active health checks of a route's upstream
+type HealthCheck struct {
+	Path         string
+	Port         int // zero means the upstream's own port
+	Interval     time.Duration
+	Timeout      time.Duration
+	ExpectStatus int // zero accepts any 2xx
+}
+
+func (d *Discovery) extractHealthCheck(container types.ContainerJSON, port int) *HealthCheck {
+	// Docker HEALTHCHECKs probing an HTTP URL provide the defaults
+	check := d.healthCheckFromDocker(container, port)
+
+	labels := container.Config.Labels
+	if path, exists := labels["devproxy.healthcheck.path"]; exists {
+		if check == nil {
+			check = &HealthCheck{
+				Interval: defaultHealthInterval,
+				Timeout:  defaultHealthTimeout,
+			}
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		check.Path = path
+	}
+
+	if check == nil {
+		return nil
+	}
+
+	if interval := d.extractDuration(container, "devproxy.healthcheck.interval"); interval > 0 {
+		check.Interval = interval
+	}
+
+	if value, exists := labels["devproxy.healthcheck.expect_status"]; exists {
+		if status, err := strconv.Atoi(value); err == nil {
+			check.ExpectStatus = status
+		} else {
+			d.logger.Warn("Ignoring invalid expect_status label", "container", container.Name, "value", value)
+		}
+	}
+
+	return check
+}
+
+// healthCheckFromDocker derives a health check from a HEALTHCHECK running
+// curl or wget against a local URL, such as
+// `curl -f http://localhost:8080/health || exit 1`
+func (d *Discovery) healthCheckFromDocker(container types.ContainerJSON, port int) *HealthCheck {
+	healthConfig := container.Config.Healthcheck
+	if healthConfig == nil || len(healthConfig.Test) < 2 {
+		return nil
+	}
+
+	var command string
+	switch healthConfig.Test[0] {
+	case "CMD":
+		command = strings.Join(healthConfig.Test[1:], " ")
+	case "CMD-SHELL":
+		command = healthConfig.Test[1]
+	default:
+		return nil
+	}
+
+	if !strings.Contains(command, "curl") && !strings.Contains(command, "wget") {
+		return nil
+	}
+
+	for _, field := range strings.Fields(command) {
+		field = strings.Trim(field, `"'`)
+		if !strings.HasPrefix(field, "http://") && !strings.HasPrefix(field, "https://") {
+			continue
+		}
+
+		u, err := url.Parse(field)
+		if err != nil {
+			return nil
+		}
+
+		check := &HealthCheck{
+			Path:     u.RequestURI(),
+			Interval: healthConfig.Interval,
+			Timeout:  healthConfig.Timeout,
+		}
+		if check.Interval == 0 {
+			check.Interval = defaultHealthInterval
+		}
+		if check.Timeout == 0 {
+			check.Timeout = defaultHealthTimeout
+		}
+		if checkPort, err := strconv.Atoi(u.Port()); err == nil && checkPort != port {
+			check.Port = checkPort
+		}
+		return check
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+)
+
+// IP families containers are dialed over, set per network
+const (
+	IPv4     = "v4"     // prefer the IPv4 address
+	IPv6     = "v6"     // prefer the IPv6 address
+	IPEither = "either" // dial both addresses of dual-stack networks
+)
+
+// AnyNetwork is the IP family key of networks not listed on their own
+const AnyNetwork = "*"
+
+// checkIPFamilies rejects unknown IP families
+func checkIPFamilies(families map[string]string) error {
+	for network, family := range families {
+		switch family {
+		case IPv4, IPv6, IPEither:
+		default:
+			return fmt.Errorf("unknown IP family %q for network %s, expected %q, %q or %q", family, network, IPv4, IPv6, IPEither)
+		}
+	}
+	return nil
+}
+
+// ipFamily returns the IP family containers are dialed over on a network
+func (d *Discovery) ipFamily(network string) string {
+	if family, found := d.endpoint.IPFamily[network]; found {
+		return family
+	}
+	if family, found := d.endpoint.IPFamily[AnyNetwork]; found {
+		return family
+	}
+	return IPEither
+}
+
+// extractContainerAddresses returns the address a container is dialed at
+// and, on dual-stack networks dialed over either family, its address of
+// the other family. Custom networks are preferred to the default bridge.
+func (d *Discovery) extractContainerAddresses(container types.ContainerJSON) (string, string) {
+	if container.NetworkSettings == nil {
+		return "", ""
+	}
+
+	// Sorted so a container on several networks keeps the same address
+	names := make([]string, 0, len(container.NetworkSettings.Networks))
+	for name := range container.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		network := container.NetworkSettings.Networks[name]
+		if name == "bridge" || network == nil {
+			continue
+		}
+		if primary, secondary := selectAddresses(network.IPAddress, network.GlobalIPv6Address, d.ipFamily(name)); primary != "" {
+			return primary, secondary
+		}
+	}
+
+	// Fallback to default bridge network
+	defaults := container.NetworkSettings.DefaultNetworkSettings
+	return selectAddresses(defaults.IPAddress, defaults.GlobalIPv6Address, d.ipFamily("bridge"))
+}
+
+// selectAddresses orders a network's addresses by IP family preference,
+// keeping the second one only to dial both families
+func selectAddresses(ipv4, ipv6, family string) (string, string) {
+	switch {
+	case family == IPv6 && ipv6 != "":
+		return ipv6, ""
+	case family == IPv4 && ipv4 != "":
+		return ipv4, ""
+	case ipv4 != "" && ipv6 != "" && family == IPEither:
+		return ipv4, ipv6
+	case ipv4 != "":
+		return ipv4, ""
+	default:
+		return ipv6, ""
+	}
+}
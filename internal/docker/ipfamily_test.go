@@ -0,0 +1,34 @@
+package docker
+
+import "testing"
+
+func TestSelectAddresses(t *testing.T) {
+	const ipv4, ipv6 = "172.18.0.5", "fd00::5"
+
+	tests := []struct {
+		name       string
+		ipv4, ipv6 string
+		family     string
+		primary    string
+		secondary  string
+	}{
+		{name: "dual stack over v4", ipv4: ipv4, ipv6: ipv6, family: IPv4, primary: ipv4},
+		{name: "dual stack over v6", ipv4: ipv4, ipv6: ipv6, family: IPv6, primary: ipv6},
+		{name: "dual stack over either", ipv4: ipv4, ipv6: ipv6, family: IPEither, primary: ipv4, secondary: ipv6},
+		{name: "v4 only over v6", ipv4: ipv4, family: IPv6, primary: ipv4},
+		{name: "v6 only over v4", ipv6: ipv6, family: IPv4, primary: ipv6},
+		{name: "v4 only over either", ipv4: ipv4, family: IPEither, primary: ipv4},
+		{name: "v6 only over either", ipv6: ipv6, family: IPEither, primary: ipv6},
+		{name: "no address", family: IPEither},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, secondary := selectAddresses(tt.ipv4, tt.ipv6, tt.family)
+			if primary != tt.primary || secondary != tt.secondary {
+				t.Errorf("selectAddresses(%q, %q, %q) = %q, %q, want %q, %q",
+					tt.ipv4, tt.ipv6, tt.family, primary, secondary, tt.primary, tt.secondary)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/docker/docker/api/types"
@@ -19,10 +20,17 @@ type Monitor struct {
 type ContainerEvent struct {
 	Action    string
 	Container types.ContainerJSON
+	Signal    string // signal of kill events, as reported by Docker
 }
 
-func NewMonitor(logger *slog.Logger) (*Monitor, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewMonitor creates a monitor of the given Docker host
+func NewMonitor(endpoint Endpoint, logger *slog.Logger) (*Monitor, error) {
+	opts, err := endpoint.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,8 +45,10 @@ func (m *Monitor) Start(ctx context.Context, eventsChan chan<- ContainerEvent) e
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("type", "container")
 	filterArgs.Add("event", "start")
+	filterArgs.Add("event", "kill")
 	filterArgs.Add("event", "stop")
 	filterArgs.Add("event", "die")
+	filterArgs.Add("event", "destroy")
 
 	eventOptions := types.EventsOptions{
 		Filters: filterArgs,
@@ -72,7 +82,19 @@ func (m *Monitor) handleEvent(ctx context.Context, event events.Message, eventsC
 		return
 	}
 
-	containerInfo, err := m.client.ContainerInspect(ctx, event.Actor.ID)
+	var containerInfo types.ContainerJSON
+	var err error
+	if event.Action == events.ActionDestroy {
+		// Removed containers can no longer be inspected
+		containerInfo = types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:   event.Actor.ID,
+				Name: "/" + event.Actor.Attributes["name"],
+			},
+		}
+	} else {
+		containerInfo, err = m.client.ContainerInspect(ctx, event.Actor.ID)
+	}
 	if err != nil {
 		m.logger.Error("Failed to inspect container", "container_id", event.Actor.ID, "error", err)
 		return
@@ -87,6 +109,7 @@ func (m *Monitor) handleEvent(ctx context.Context, event events.Message, eventsC
 	case eventsChan <- ContainerEvent{
 		Action:    string(event.Action),
 		Container: containerInfo,
+		Signal:    event.Actor.Attributes["signal"],
 	}:
 	case <-ctx.Done():
 		return
@@ -104,6 +127,49 @@ func (m *Monitor) GetRunningContainers(ctx context.Context) ([]types.Container,
 	return containers, nil
 }
 
+// GetAllContainers lists containers in any state, including stopped ones,
+// optionally only those carrying all of the given labels ("key=value")
+func (m *Monitor) GetAllContainers(ctx context.Context, labels ...string) ([]types.Container, error) {
+	filterArgs := filters.NewArgs()
+	for _, label := range labels {
+		filterArgs.Add("label", label)
+	}
+
+	return m.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterArgs,
+	})
+}
+
+// StartContainer starts a stopped container
+func (m *Monitor) StartContainer(ctx context.Context, containerID string) error {
+	return m.client.ContainerStart(ctx, containerID, container.StartOptions{})
+}
+
+// StopContainer stops a running container with Docker's default timeout
+func (m *Monitor) StopContainer(ctx context.Context, containerID string) error {
+	return m.client.ContainerStop(ctx, containerID, container.StopOptions{})
+}
+
 func (m *Monitor) InspectContainer(ctx context.Context, containerID string) (types.ContainerJSON, error) {
 	return m.client.ContainerInspect(ctx, containerID)
 }
+
+// FindComposeService returns a running container of the given compose service
+func (m *Monitor) FindComposeService(ctx context.Context, project, service string) (types.ContainerJSON, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "com.docker.compose.project="+project)
+	filterArgs.Add("label", "com.docker.compose.service="+service)
+
+	containers, err := m.client.ContainerList(ctx, container.ListOptions{
+		Filters: filterArgs,
+	})
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	if len(containers) == 0 {
+		return types.ContainerJSON{}, fmt.Errorf("no running container for service %s in project %s", service, project)
+	}
+
+	return m.client.ContainerInspect(ctx, containers[0].ID)
+}
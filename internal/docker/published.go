@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+)
+
+// extractPublishedTargets returns the routes of a running container dialed
+// at the port Docker publishes for its target port on the Docker host.
+// Containers that do not publish that port cannot be reached and get no
+// routes.
+func (d *Discovery) extractPublishedTargets(container types.ContainerJSON) []ProxyTarget {
+	targets := d.extractTargets(container, d.endpoint.publishedHost())
+	if len(targets) == 0 {
+		return nil
+	}
+
+	host := d.endpoint.publishedHost()
+	containerPort := targets[0].Port
+	binding, found := publishedBinding(container, containerPort)
+	if !found {
+		d.warnOnce(container, "Container does not publish its target port, it cannot be routed with published ports",
+			"container", container.Name,
+			"endpoint", d.endpoint.Name,
+			"port", containerPort,
+			"hint", "publish the port, e.g. ports: [\""+strconv.Itoa(containerPort)+"\"], or set devproxy.port to a published port")
+		return nil
+	}
+	hostPort, _ := strconv.Atoi(binding.HostPort)
+
+	// A port published on the loopback interface only is out of reach
+	// unless Caddy dials it on the same machine
+	if isLoopback(binding.HostIP) && !isLoopback(host) {
+		d.warnOnce(container, "Container publishes its target port on the loopback interface only, the proxy may not reach it",
+			"container", container.Name,
+			"endpoint", d.endpoint.Name,
+			"port", containerPort,
+			"published", net.JoinHostPort(binding.HostIP, binding.HostPort),
+			"dialed", net.JoinHostPort(host, binding.HostPort),
+			"hint", "publish the port on all interfaces, or set the published host to "+binding.HostIP)
+	}
+
+	// Health checks on another container port go through its published
+	// port too, or probe the upstream when it is not published
+	var check *HealthCheck
+	if targets[0].HealthCheck != nil {
+		mapped := *targets[0].HealthCheck
+		if mapped.Port != 0 {
+			mapped.Port = publishedPort(container, mapped.Port)
+		}
+		check = &mapped
+	}
+
+	for i := range targets {
+		targets[i].Port = hostPort
+		targets[i].HealthCheck = check
+	}
+
+	return targets
+}
+
+// publishedPort returns the host port Docker publishes a container's TCP
+// port on, or zero when it is not published
+func publishedPort(container types.ContainerJSON, port int) int {
+	binding, found := publishedBinding(container, port)
+	if !found {
+		return 0
+	}
+	hostPort, _ := strconv.Atoi(binding.HostPort)
+	return hostPort
+}
+
+// publishedBinding returns the binding publishing a container's TCP port,
+// preferring bindings on every interface to loopback-only ones
+func publishedBinding(container types.ContainerJSON, port int) (nat.PortBinding, bool) {
+	if container.NetworkSettings == nil {
+		return nat.PortBinding{}, false
+	}
+
+	var loopback *nat.PortBinding
+	for _, binding := range container.NetworkSettings.Ports[nat.Port(strconv.Itoa(port)+"/tcp")] {
+		if hostPort, err := strconv.Atoi(binding.HostPort); err != nil || hostPort == 0 {
+			continue
+		}
+		if !isLoopback(binding.HostIP) {
+			return binding, true
+		}
+		if loopback == nil {
+			loopback = &binding
+		}
+	}
+
+	if loopback != nil {
+		return *loopback, true
+	}
+	return nat.PortBinding{}, false
+}
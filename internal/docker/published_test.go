@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestPublishedBinding(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    nat.PortMap
+		binding  nat.PortBinding
+		notFound bool
+	}{
+		{
+			name:    "every interface",
+			ports:   nat.PortMap{"3000/tcp": {{HostIP: "0.0.0.0", HostPort: "49153"}}},
+			binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "49153"},
+		},
+		{
+			name: "every interface preferred to loopback",
+			ports: nat.PortMap{"3000/tcp": {
+				{HostIP: "127.0.0.1", HostPort: "3000"},
+				{HostIP: "::", HostPort: "3001"},
+			}},
+			binding: nat.PortBinding{HostIP: "::", HostPort: "3001"},
+		},
+		{
+			name: "first loopback binding",
+			ports: nat.PortMap{"3000/tcp": {
+				{HostIP: "127.0.0.1", HostPort: "3000"},
+				{HostIP: "::1", HostPort: "3001"},
+			}},
+			binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "3000"},
+		},
+		{
+			name:    "unassigned host ports are skipped",
+			ports:   nat.PortMap{"3000/tcp": {{HostIP: "0.0.0.0", HostPort: ""}, {HostIP: "0.0.0.0", HostPort: "0"}, {HostIP: "127.0.0.1", HostPort: "3000"}}},
+			binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "3000"},
+		},
+		{
+			name:     "exposed but not published",
+			ports:    nat.PortMap{"3000/tcp": nil},
+			notFound: true,
+		},
+		{
+			name:     "udp only",
+			ports:    nat.PortMap{"3000/udp": {{HostIP: "0.0.0.0", HostPort: "3000"}}},
+			notFound: true,
+		},
+		{
+			name:     "other port",
+			ports:    nat.PortMap{"8080/tcp": {{HostIP: "0.0.0.0", HostPort: "8080"}}},
+			notFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := types.ContainerJSON{
+				NetworkSettings: &types.NetworkSettings{
+					NetworkSettingsBase: types.NetworkSettingsBase{Ports: tt.ports},
+				},
+			}
+
+			binding, found := publishedBinding(container, 3000)
+			if found == tt.notFound {
+				t.Fatalf("publishedBinding() found = %v, want %v", found, !tt.notFound)
+			}
+			if binding != tt.binding {
+				t.Errorf("publishedBinding() = %+v, want %+v", binding, tt.binding)
+			}
+		})
+	}
+
+	t.Run("no network settings", func(t *testing.T) {
+		if binding, found := publishedBinding(types.ContainerJSON{}, 3000); found {
+			t.Errorf("publishedBinding() = %+v, want none", binding)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"sort"
+)
+
+// Conflict describes several containers claiming the same domain and path.
+// The winner is the target that gets routed; the others are ignored.
+type Conflict struct {
+	Domain string
+	Path   string
+	Winner ProxyTarget
+	Losers []ProxyTarget
+}
+
+// SortTargets orders targets by routing precedence: longest host first, then
+// longest path, then highest priority. Remaining ties are broken by name so
+// the order is stable across runs.
+func SortTargets(targets []ProxyTarget) {
+	sort.SliceStable(targets, func(i, j int) bool {
+		a, b := targets[i], targets[j]
+		if len(a.Domain) != len(b.Domain) {
+			return len(a.Domain) > len(b.Domain)
+		}
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return claimsBefore(a, b)
+	})
+}
+
+// ResolveConflicts keeps a single target per domain and path, returning the
+// winners in routing order along with every conflict that was detected.
+func ResolveConflicts(targets []ProxyTarget) ([]ProxyTarget, []Conflict) {
+	sorted := make([]ProxyTarget, len(targets))
+	copy(sorted, targets)
+	SortTargets(sorted)
+
+	var winners []ProxyTarget
+	var conflicts []Conflict
+	for i := 0; i < len(sorted); {
+		// Claimants of the same route are adjacent, best claim first
+		j := i + 1
+		for j < len(sorted) && sorted[j].Domain == sorted[i].Domain && sorted[j].Path == sorted[i].Path {
+			j++
+		}
+
+		winners = append(winners, sorted[i])
+		if j-i > 1 {
+			conflicts = append(conflicts, Conflict{
+				Domain: sorted[i].Domain,
+				Path:   sorted[i].Path,
+				Winner: sorted[i],
+				Losers: append([]ProxyTarget(nil), sorted[i+1:j]...),
+			})
+		}
+		i = j
+	}
+
+	return winners, conflicts
+}
+
+// claimsBefore reports whether a takes precedence over b for the same route
+func claimsBefore(a, b ProxyTarget) bool {
+	// A running container always wins over a stopped one
+	if a.Stopped != b.Stopped {
+		return !a.Stopped
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if a.ContainerName != b.ContainerName {
+		return a.ContainerName < b.ContainerName
+	}
+	return a.ContainerID < b.ContainerID
+}
@@ -0,0 +1,208 @@
+package hostscan
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the socket state of listening sockets in /proc/net/tcp
+const tcpListen = "0A"
+
+// Listener is a listening TCP socket on the host
+type Listener struct {
+	Address net.IP
+	Port    int
+	Inode   uint64
+	PID     int    // zero when the owning process could not be found
+	Process string // name derived from the process, empty when unknown
+}
+
+// Loopback reports whether the socket only accepts local connections
+func (l Listener) Loopback() bool {
+	return l.Address.IsLoopback()
+}
+
+// ScanListeners lists the listening TCP sockets of the host's network
+// namespace and the processes owning them. procRoot is where the host's
+// /proc is visible; with a host PID namespace, procRoot/1/net is the host's
+// network namespace even from inside a container.
+func ScanListeners(procRoot string) ([]Listener, error) {
+	netDir := filepath.Join(procRoot, "1", "net")
+	if _, err := os.Stat(filepath.Join(netDir, "tcp")); err != nil {
+		netDir = filepath.Join(procRoot, "net")
+	}
+
+	var listeners []Listener
+	for _, name := range []string{"tcp", "tcp6"} {
+		found, err := readSockets(filepath.Join(netDir, name))
+		if err != nil {
+			// IPv6 may be disabled
+			if name == "tcp6" && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		listeners = append(listeners, found...)
+	}
+
+	owners := socketOwners(procRoot)
+	for i := range listeners {
+		if pid, found := owners[listeners[i].Inode]; found {
+			listeners[i].PID = pid
+			listeners[i].Process = processName(procRoot, pid)
+		}
+	}
+
+	return listeners, nil
+}
+
+// readSockets parses the listening sockets of a /proc/net/tcp{,6} file
+func readSockets(path string) ([]Listener, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var listeners []Listener
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+
+		address, port, err := parseAddress(fields[1])
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		listeners = append(listeners, Listener{
+			Address: address,
+			Port:    port,
+			Inode:   inode,
+		})
+	}
+
+	return listeners, scanner.Err()
+}
+
+// parseAddress decodes "0100007F:1F90". The address is stored as 32-bit
+// words in host byte order, little endian on the platforms Docker runs on.
+func parseAddress(value string) (net.IP, int, error) {
+	hexIP, hexPort, found := strings.Cut(value, ":")
+	if !found {
+		return nil, 0, fmt.Errorf("invalid socket address %q", value)
+	}
+
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid socket address %q", value)
+	}
+	for word := 0; word < len(raw); word += 4 {
+		raw[word], raw[word+1], raw[word+2], raw[word+3] = raw[word+3], raw[word+2], raw[word+1], raw[word]
+	}
+
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid socket port %q", value)
+	}
+
+	return net.IP(raw), int(port), nil
+}
+
+// socketOwners maps socket inodes to the PID of a process holding them.
+// Processes whose file descriptors cannot be read are skipped.
+func socketOwners(procRoot string) map[uint64]int {
+	owners := make(map[uint64]int)
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return owners
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join(procRoot, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			inode, found := strings.CutPrefix(link, "socket:[")
+			if !found {
+				continue
+			}
+			if n, err := strconv.ParseUint(strings.TrimSuffix(inode, "]"), 10, 64); err == nil {
+				if _, seen := owners[n]; !seen {
+					owners[n] = pid
+				}
+			}
+		}
+	}
+
+	return owners
+}
+
+// interpreters run the program named by their first argument, which names
+// the dev server better than the interpreter itself
+var interpreters = map[string]bool{
+	"node": true, "bun": true, "deno": true,
+	"python": true, "python3": true,
+	"ruby": true, "php": true, "java": true,
+}
+
+// processName returns the command name of a process, or for interpreters
+// the script or module they run, e.g. "vite" for "node .../bin/vite"
+func processName(procRoot string, pid int) string {
+	comm, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(comm))
+
+	if !interpreters[name] {
+		return name
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return name
+	}
+
+	args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-m" && i+1 < len(args):
+			// python -m module
+			return args[i+1]
+		case strings.HasPrefix(arg, "-"):
+			continue
+		default:
+			script := filepath.Base(arg)
+			return strings.TrimSuffix(script, filepath.Ext(script))
+		}
+	}
+
+	return name
+}
@@ -0,0 +1,44 @@
+package hostscan
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		value   string
+		ip      string
+		port    int
+		wantErr bool
+	}{
+		{value: "0100007F:1F90", ip: "127.0.0.1", port: 8080},
+		{value: "00000000:0050", ip: "0.0.0.0", port: 80},
+		{value: "0A01A8C0:1435", ip: "192.168.1.10", port: 5173},
+		{value: "00000000000000000000000001000000:0BB8", ip: "::1", port: 3000},
+		{value: "00000000000000000000000000000000:1F40", ip: "::", port: 8000},
+		{value: "000000FD000000000000000005000000:0016", ip: "fd00::5", port: 22},
+		{value: "0000FFFF0100007F:1F90", wantErr: true},
+		{value: "0100007F", wantErr: true},
+		{value: "ZZ00007F:1F90", wantErr: true},
+		{value: "0100007F:FFFFF", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ip, port, err := parseAddress(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAddress(%q) = %v, %d, want an error", tt.value, ip, port)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAddress(%q): %v", tt.value, err)
+			}
+			if !ip.Equal(net.ParseIP(tt.ip)) || port != tt.port {
+				t.Errorf("parseAddress(%q) = %v, %d, want %s, %d", tt.value, ip, port, tt.ip, tt.port)
+			}
+		})
+	}
+}
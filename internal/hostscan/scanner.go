@@ -0,0 +1,134 @@
+package hostscan
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"devproxy/internal/docker"
+)
+
+// Source tags targets of dev servers found listening on the host
+const Source = "host"
+
+// Scanner turns the host's listening sockets into proxy targets
+type Scanner struct {
+	ProcRoot     string
+	Upstream     string // how Caddy reaches the host, e.g. host.docker.internal
+	DomainSuffix string
+	Include      []string // rules a listener must match, all when empty
+	Exclude      []string // rules a listener must not match
+}
+
+// Scan lists the host's listeners and returns a target for each one
+// selected by the include and exclude rules
+func (s *Scanner) Scan() ([]docker.ProxyTarget, error) {
+	listeners, err := ScanListeners(s.ProcRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Targets(listeners), nil
+}
+
+// Targets routes each selected listener to "<process>-<port>.<suffix>", and
+// additionally to "<process>.<suffix>" when a known process listens on a
+// single port. Listeners bound to loopback only cannot be reached from
+// Caddy's container and are left out.
+func (s *Scanner) Targets(listeners []Listener) []docker.ProxyTarget {
+	byPort := make(map[int]Listener)
+	for _, listener := range listeners {
+		if listener.Loopback() || !s.selected(listener) {
+			continue
+		}
+		// The IPv4 and IPv6 sockets of a dual-stack server share a port
+		if existing, found := byPort[listener.Port]; found && existing.Process != "" {
+			continue
+		}
+		byPort[listener.Port] = listener
+	}
+
+	ports := make([]int, 0, len(byPort))
+	portsByName := make(map[string]int)
+	for port, listener := range byPort {
+		ports = append(ports, port)
+		portsByName[label(listener.Process)]++
+	}
+	sort.Ints(ports)
+
+	var targets []docker.ProxyTarget
+	for _, port := range ports {
+		listener := byPort[port]
+		name := label(listener.Process)
+
+		domains := []string{name + "-" + strconv.Itoa(port) + "." + s.DomainSuffix}
+		if listener.Process != "" && portsByName[name] == 1 {
+			domains = append(domains, name+"."+s.DomainSuffix)
+		}
+
+		for _, domain := range domains {
+			targets = append(targets, docker.ProxyTarget{
+				Domain:        domain,
+				ContainerName: name,
+				ContainerIP:   s.Upstream,
+				Port:          port,
+				TLSMode:       docker.TLSModeBoth,
+				Scheme:        docker.SchemeHTTP,
+				Source:        Source,
+			})
+		}
+	}
+
+	return targets
+}
+
+// selected applies the include and exclude rules to a listener
+func (s *Scanner) selected(listener Listener) bool {
+	for _, rule := range s.Exclude {
+		if matches(rule, listener) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, rule := range s.Include {
+		if matches(rule, listener) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a rule selects a listener. A rule is a process
+// name, a port, or "name:port".
+func matches(rule string, listener Listener) bool {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return false
+	}
+
+	name, portStr, hasPort := strings.Cut(rule, ":")
+	if !hasPort {
+		if port, err := strconv.Atoi(rule); err == nil {
+			return port == listener.Port
+		}
+		return name == listener.Process
+	}
+
+	port, err := strconv.Atoi(portStr)
+	return err == nil && port == listener.Port && name == listener.Process
+}
+
+var invalidLabel = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// label turns a process name into a DNS label, falling back to "host" for
+// sockets whose process is unknown
+func label(process string) string {
+	name := strings.Trim(invalidLabel.ReplaceAllString(strings.ToLower(process), "-"), "-")
+	if name == "" {
+		return Source
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"devproxy/internal/caddy"
+	"devproxy/internal/docker"
+
+	"github.com/docker/docker/api/types"
+)
+
+// handleAutostart serves requests for stopped containers labelled
+// devproxy.autostart. The first request starts the container; every request
+// gets a page that reloads until the container accepts connections and its
+// route switches back to proxying.
+func (m *Manager) handleAutostart(w http.ResponseWriter, r *http.Request) {
+	containerID := r.Header.Get(caddy.AutostartHeader)
+	for _, provider := range m.dockers {
+		if target, found := provider.autostartTarget(containerID); found {
+			provider.serveAutostart(w, target)
+			return
+		}
+	}
+
+	http.Error(w, "No stopped container to start for this route", http.StatusNotFound)
+}
+
+// serveAutostart starts the target's container and serves the starting page
+func (p *DockerProvider) serveAutostart(w http.ResponseWriter, target docker.ProxyTarget) {
+	p.startContainer(target)
+
+	pages, err := caddy.LoadPages(p.config.DevProxy.TemplatesDir)
+	if err != nil {
+		p.logger.Error("Failed to load page templates", "error", err)
+		http.Error(w, "Starting container, retry shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := pages.Render(caddy.PageStarting, caddy.PageData{
+		Domain:         target.Domain,
+		ContainerName:  target.ContainerName,
+		ContainerState: target.ContainerState,
+		Upstream:       strconv.Itoa(target.Port),
+		StatusCode:     strconv.Itoa(http.StatusServiceUnavailable),
+		DashboardURL:   p.config.DevProxy.URL("https", p.config.Dashboard.Domain),
+	})
+	if err != nil {
+		p.logger.Error("Failed to render starting page", "error", err)
+		http.Error(w, "Starting container, retry shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", "2")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	io.WriteString(w, body)
+}
+
+// autostartTarget returns a stopped route of the container that may be
+// started on request
+func (p *DockerProvider) autostartTarget(containerID string) (docker.ProxyTarget, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, target := range p.proxyTargets[containerID] {
+		if target.Stopped && target.Autostart && target.Crash == nil {
+			return target, true
+		}
+	}
+	return docker.ProxyTarget{}, false
+}
+
+// startContainer starts the target's container in the background, once no
+// matter how many requests arrive while it is starting
+func (p *DockerProvider) startContainer(target docker.ProxyTarget) {
+	p.mu.Lock()
+	if p.starting[target.ContainerID] {
+		p.mu.Unlock()
+		return
+	}
+	p.starting[target.ContainerID] = true
+	p.mu.Unlock()
+
+	p.logger.Info("Starting container on request",
+		"domain", target.Domain,
+		"container", target.ContainerName)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.DevProxy.AutostartTimeout)
+		defer cancel()
+
+		container, err := p.startAndWait(ctx, target)
+		if err != nil {
+			p.logger.Warn("Failed to start container on request",
+				"container", target.ContainerName,
+				"error", err)
+		}
+
+		p.mu.Lock()
+		delete(p.starting, target.ContainerID)
+		p.mu.Unlock()
+
+		// Route changes belong to the event loop; it switches the route to
+		// proxying, or keeps it stopped if the container did not come up
+		if container.ContainerJSONBase == nil {
+			return
+		}
+		action := "start"
+		if !container.State.Running {
+			action = "die"
+		}
+		p.autostarted <- docker.ContainerEvent{
+			Action:    action,
+			Container: container,
+		}
+	}()
+}
+
+// startAndWait starts a container and waits until it accepts connections on
+// its routed port, returning its last known state
+func (p *DockerProvider) startAndWait(ctx context.Context, target docker.ProxyTarget) (types.ContainerJSON, error) {
+	if err := p.monitor.StartContainer(ctx, target.ContainerID); err != nil {
+		return types.ContainerJSON{}, err
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		container, err := p.monitor.InspectContainer(ctx, target.ContainerID)
+		if err != nil {
+			return types.ContainerJSON{}, err
+		}
+		if !container.State.Running {
+			return container, fmt.Errorf("container exited with code %d", container.State.ExitCode)
+		}
+
+		if targets := p.discovery.ExtractProxyTargets(container); len(targets) > 0 {
+			conn, err := net.DialTimeout("tcp", targets[0].Dial(), time.Second)
+			if err == nil {
+				conn.Close()
+				return container, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return container, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"devproxy/internal/docker"
+)
+
+// Client talks to the manager's HTTP server from another process, such as
+// the dashboard
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	logger     *slog.Logger
+}
+
+func NewClient(baseURL string, logger *slog.Logger) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// GetIdleStates returns the idle shutdown state of the manager's containers
+func (c *Client) GetIdleStates(ctx context.Context) ([]IdleState, error) {
+	url := fmt.Sprintf("%s/api/idle", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("manager API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var states []IdleState
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		return nil, fmt.Errorf("failed to decode idle states: %w", err)
+	}
+
+	return states, nil
+}
+
+// GetHostTargets returns the routes of dev servers the manager found
+// listening on the host
+func (c *Client) GetHostTargets(ctx context.Context) ([]docker.ProxyTarget, error) {
+	url := fmt.Sprintf("%s/api/host", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("manager API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var targets []docker.ProxyTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("failed to decode host targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+// GetProviderStatuses returns the health of the manager's providers
+func (c *Client) GetProviderStatuses(ctx context.Context) ([]ProviderStatus, error) {
+	url := fmt.Sprintf("%s/api/providers", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("manager API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var statuses []ProviderStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return nil, fmt.Errorf("failed to decode provider statuses: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// SetIdleExempt keeps a container from idle shutdown for the rest of the
+// day, or lifts that exemption
+func (c *Client) SetIdleExempt(ctx context.Context, containerID string, exempt bool) error {
+	// The ID comes from the dashboard's query string and must stay a single
+	// path segment
+	endpoint := fmt.Sprintf("%s/api/idle/%s/exempt", c.baseURL, url.PathEscape(containerID))
+
+	method := "DELETE"
+	if exempt {
+		method = "POST"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("manager API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
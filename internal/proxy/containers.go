@@ -0,0 +1,552 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"devproxy/internal/caddy"
+	"devproxy/internal/config"
+	"devproxy/internal/docker"
+
+	"github.com/docker/docker/api/types"
+)
+
+// DockerProvider discovers targets from container labels and follows
+// container lifecycle events. It also owns what the manager does to
+// containers: keeping routes of stopped and crashed containers, starting
+// containers on request and stopping idle ones.
+type DockerProvider struct {
+	config      *config.Config
+	endpoint    docker.Endpoint
+	monitor     *docker.Monitor
+	discovery   *docker.Discovery
+	caddyClient *caddy.Client
+	logger      *slog.Logger
+
+	mu             sync.RWMutex
+	updates        chan<- TargetSet
+	err            error
+	proxyTargets   map[string][]docker.ProxyTarget // container ID -> targets
+	pendingRemoval map[string]pendingRemoval       // container ID -> removal
+	killed         map[string]bool                 // containers stopped on request
+	starting       map[string]bool                 // containers started on request
+	autostarted    chan docker.ContainerEvent
+	lastActivity   map[string]time.Time // container ID -> last request
+	idleExempt     map[string]time.Time // container ID -> end of idle exemption
+}
+
+func NewDockerProvider(cfg *config.Config, endpoint docker.Endpoint, caddyClient *caddy.Client, logger *slog.Logger) (*DockerProvider, error) {
+	logger = logger.With("endpoint", endpoint.Name)
+
+	monitor, err := docker.NewMonitor(endpoint, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DockerProvider{
+		config:         cfg,
+		endpoint:       endpoint,
+		monitor:        monitor,
+		discovery:      docker.NewDiscovery(endpoint, logger),
+		caddyClient:    caddyClient,
+		logger:         logger,
+		proxyTargets:   make(map[string][]docker.ProxyTarget),
+		pendingRemoval: make(map[string]pendingRemoval),
+		killed:         make(map[string]bool),
+		starting:       make(map[string]bool),
+		autostarted:    make(chan docker.ContainerEvent, 10),
+		lastActivity:   make(map[string]time.Time),
+		idleExempt:     make(map[string]time.Time),
+	}, nil
+}
+
+// pendingRemoval delays the removal of a stopped container's routes
+type pendingRemoval struct {
+	deadline time.Time
+	stopped  []docker.ProxyTarget // replacement routes, if stopped routes are kept
+}
+
+// Name is "docker" for the default Docker host and "docker:<endpoint>" for
+// the others
+func (p *DockerProvider) Name() string {
+	if p.endpoint.Name == docker.DefaultEndpoint {
+		return docker.SourceDocker
+	}
+	return docker.SourceDocker + ":" + p.endpoint.Name
+}
+
+// Endpoint returns the name of the provider's Docker host
+func (p *DockerProvider) Endpoint() string {
+	return p.endpoint.Name
+}
+
+// Run syncs the existing containers, then follows Docker events until ctx
+// is canceled
+func (p *DockerProvider) Run(ctx context.Context, updates chan<- TargetSet) error {
+	p.mu.Lock()
+	p.updates = updates
+	p.mu.Unlock()
+
+	// Subscribing before listing containers leaves no gap where events are
+	// lost. Events arriving during the sync are replayed after it. The
+	// subscription ends with this run.
+	eventsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eventsChan := make(chan docker.ContainerEvent, 10)
+	if err := p.monitor.Start(eventsCtx, eventsChan); err != nil {
+		p.setHealth(err)
+		return err
+	}
+
+	buffered, streamEnded, err := p.syncWhileBuffering(ctx, eventsChan)
+	if err != nil {
+		p.setHealth(err)
+		return err
+	}
+	p.setHealth(nil)
+	p.publish(ctx)
+
+	for _, event := range buffered {
+		p.handleContainerEvent(ctx, event)
+	}
+	if streamEnded {
+		if ctx.Err() != nil {
+			return nil
+		}
+		err := errors.New("docker event stream ended")
+		p.setHealth(err)
+		return err
+	}
+
+	p.logger.Info("Monitoring Docker containers...")
+
+	removalTicker := time.NewTicker(time.Second)
+	defer removalTicker.Stop()
+
+	idleTicker := time.NewTicker(15 * time.Second)
+	defer idleTicker.Stop()
+
+	for {
+		select {
+		case event, ok := <-eventsChan:
+			if !ok {
+				// The monitor closes the channel when the event stream fails
+				if ctx.Err() != nil {
+					return nil
+				}
+				err := errors.New("docker event stream ended")
+				p.setHealth(err)
+				return err
+			}
+			p.handleContainerEvent(ctx, event)
+		case event := <-p.autostarted:
+			p.handleContainerEvent(ctx, event)
+		case <-removalTicker.C:
+			p.expirePendingRemovals(ctx)
+		case <-idleTicker.C:
+			p.stopIdleContainers(ctx)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Snapshot runs discovery against the containers currently in Docker,
+// independently of the provider's own state
+func (p *DockerProvider) Snapshot(ctx context.Context) ([]docker.ProxyTarget, error) {
+	containers, err := p.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []docker.ProxyTarget
+	for _, container := range containers {
+		containerInfo, err := p.monitor.InspectContainer(ctx, container.ID)
+		if err != nil {
+			p.logger.Warn("Failed to inspect container", "container_id", container.ID, "error", err)
+			continue
+		}
+		targets = append(targets, p.ExtractTargets(containerInfo)...)
+	}
+
+	return targets, nil
+}
+
+func (p *DockerProvider) Health() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.err
+}
+
+func (p *DockerProvider) setHealth(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+}
+
+// syncWhileBuffering syncs the existing containers while collecting the
+// events received meanwhile, so the event stream is never left unread
+func (p *DockerProvider) syncWhileBuffering(ctx context.Context, eventsChan <-chan docker.ContainerEvent) ([]docker.ContainerEvent, bool, error) {
+	synced := make(chan error, 1)
+	go func() {
+		synced <- p.syncExistingContainers(ctx)
+	}()
+
+	var buffered []docker.ContainerEvent
+	streamEnded := false
+	for {
+		select {
+		case event, ok := <-eventsChan:
+			if !ok {
+				// Keep waiting so the sync does not outlive this run
+				streamEnded = true
+				eventsChan = nil
+				continue
+			}
+			buffered = append(buffered, event)
+		case err := <-synced:
+			return buffered, streamEnded, err
+		}
+	}
+}
+
+// publish sends the routes of every known container to the manager
+func (p *DockerProvider) publish(ctx context.Context) {
+	p.mu.RLock()
+	updates := p.updates
+	containerKeys := make([]string, 0, len(p.proxyTargets))
+	for containerKey := range p.proxyTargets {
+		containerKeys = append(containerKeys, containerKey)
+	}
+	// Map order is random, containers are published in a stable order
+	sort.Strings(containerKeys)
+	var targets []docker.ProxyTarget
+	for _, containerKey := range containerKeys {
+		targets = append(targets, p.proxyTargets[containerKey]...)
+	}
+	p.mu.RUnlock()
+
+	if updates == nil {
+		return
+	}
+	publish(ctx, updates, TargetSet{Provider: p.Name(), Targets: targets})
+}
+
+// syncExistingContainers rebuilds the routes from the containers already in
+// Docker, dropping those of containers that went away while the provider
+// was not following events
+func (p *DockerProvider) syncExistingContainers(ctx context.Context) error {
+	containers, err := p.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("Syncing existing containers", "count", len(containers))
+
+	listed := make(map[string]bool)
+	for _, container := range containers {
+		listed[container.ID] = true
+	}
+
+	p.mu.Lock()
+	p.proxyTargets = make(map[string][]docker.ProxyTarget)
+	p.pendingRemoval = make(map[string]pendingRemoval)
+	p.killed = make(map[string]bool)
+	for _, state := range []map[string]time.Time{p.lastActivity, p.idleExempt} {
+		for containerID := range state {
+			if !listed[containerID] {
+				delete(state, containerID)
+			}
+		}
+	}
+	for containerID := range p.starting {
+		if !listed[containerID] {
+			delete(p.starting, containerID)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, container := range containers {
+		containerInfo, err := p.monitor.InspectContainer(ctx, container.ID)
+		if err != nil {
+			p.logger.Warn("Failed to inspect container", "container_id", container.ID, "error", err)
+			continue
+		}
+
+		// How past containers stopped is unknown, so none count as crashed
+		if containerInfo.State.Running {
+			p.addContainer(ctx, containerInfo)
+		} else {
+			p.removeContainer(ctx, containerInfo, false)
+		}
+	}
+
+	return nil
+}
+
+// handleContainerEvent updates routes for a container's lifecycle event and
+// publishes the result
+func (p *DockerProvider) handleContainerEvent(ctx context.Context, event docker.ContainerEvent) {
+	containerKey := p.discovery.GetContainerKey(event.Container)
+
+	switch event.Action {
+	case "kill":
+		// docker stop and restart kill the container before it dies, crashes
+		// do not. Signals the container handles and survives are ignored.
+		if !docker.StopSignal(event.Container, event.Signal) {
+			return
+		}
+		p.mu.Lock()
+		p.killed[containerKey] = true
+		p.mu.Unlock()
+		return
+	case "start":
+		p.mu.Lock()
+		delete(p.killed, containerKey)
+		starting := p.starting[containerKey]
+		p.mu.Unlock()
+
+		// Containers started on request keep their starting page until
+		// they accept connections
+		if starting {
+			return
+		}
+		p.addContainer(ctx, event.Container)
+	case "die":
+		// The kill only explains this exit, a later one may be a crash
+		p.mu.Lock()
+		killed := p.killed[containerKey]
+		delete(p.killed, containerKey)
+		p.mu.Unlock()
+		p.removeContainer(ctx, event.Container, !killed && docker.Crashed(event.Container))
+	case "stop":
+		// Only docker stop and restart send stop events
+		p.removeContainer(ctx, event.Container, false)
+	case "destroy":
+		p.mu.Lock()
+		delete(p.killed, containerKey)
+		p.mu.Unlock()
+		p.forgetContainer(event.Container)
+	default:
+		return
+	}
+
+	p.publish(ctx)
+}
+
+func (p *DockerProvider) addContainer(ctx context.Context, container types.ContainerJSON) {
+	targets := p.discovery.ExtractProxyTargets(container)
+	containerKey := p.discovery.GetContainerKey(container)
+
+	if len(targets) == 0 {
+		// Drop routes kept while the container was stopped
+		p.forgetContainer(container)
+		return
+	}
+
+	p.mu.Lock()
+	p.proxyTargets[containerKey] = targets
+	delete(p.pendingRemoval, containerKey)
+	// Idle time counts from the start
+	p.lastActivity[containerKey] = time.Now()
+	p.mu.Unlock()
+
+	for _, target := range targets {
+		p.logger.Info("Added proxy target",
+			"domain", target.Domain,
+			"container_ip", target.ContainerIP,
+			"port", target.Port,
+			"container", container.Name)
+	}
+}
+
+// removeContainer replaces the routes of a container that is no longer
+// running. Crashed containers keep their routes, serving diagnostics, even
+// when stopped routes are not kept.
+func (p *DockerProvider) removeContainer(ctx context.Context, container types.ContainerJSON, crashed bool) {
+	containerKey := p.discovery.GetContainerKey(container)
+
+	stopped := p.stoppedTargets(container, crashed)
+	if crashed && len(stopped) > 0 {
+		report := p.crashReport(ctx, container)
+		for i := range stopped {
+			stopped[i].Crash = report
+		}
+	}
+
+	p.mu.Lock()
+	targets, exists := p.proxyTargets[containerKey]
+
+	// Routes with retries stay up for their retry window, so requests made
+	// during a restart wait for the container instead of failing
+	var retryWindow time.Duration
+	for _, target := range targets {
+		if !target.Stopped {
+			retryWindow = max(retryWindow, target.TryDuration)
+		}
+	}
+
+	if exists && retryWindow > 0 {
+		if _, pending := p.pendingRemoval[containerKey]; !pending {
+			p.pendingRemoval[containerKey] = pendingRemoval{
+				deadline: time.Now().Add(retryWindow),
+				stopped:  stopped,
+			}
+		}
+		p.mu.Unlock()
+		return
+	}
+
+	if len(stopped) > 0 {
+		p.proxyTargets[containerKey] = stopped
+	} else if exists {
+		delete(p.proxyTargets, containerKey)
+	}
+	p.mu.Unlock()
+
+	if len(stopped) > 0 {
+		for _, target := range stopped {
+			p.logger.Info("Serving stopped page",
+				"domain", target.Domain,
+				"container", target.ContainerName,
+				"exit_code", target.ExitCode)
+		}
+	} else if exists {
+		for _, target := range targets {
+			p.logger.Info("Removed proxy target",
+				"domain", target.Domain,
+				"container", container.Name)
+		}
+	}
+}
+
+// stoppedTargets returns the routes kept for a container that is not
+// running, or nil when its routes go away
+func (p *DockerProvider) stoppedTargets(container types.ContainerJSON, crashed bool) []docker.ProxyTarget {
+	stopped := p.discovery.ExtractStoppedTargets(container)
+	if len(stopped) == 0 {
+		return nil
+	}
+	if !p.config.DevProxy.KeepStopped && !crashed && !stopped[0].Autostart {
+		return nil
+	}
+	return stopped
+}
+
+// crashReport collects the exit details and last log lines of a crashed
+// container. Logs that cannot be read are left out of the report.
+func (p *DockerProvider) crashReport(ctx context.Context, container types.ContainerJSON) *docker.CrashReport {
+	logs, err := p.monitor.TailLogs(ctx, container, p.config.DevProxy.CrashLogLines)
+	if err != nil {
+		p.logger.Warn("Failed to read container logs", "container", container.Name, "error", err)
+	}
+
+	report := docker.NewCrashReport(container, logs)
+	p.logger.Warn("Container crashed",
+		"container", container.Name,
+		"exit_code", container.State.ExitCode,
+		"oom_killed", report.OOMKilled,
+		"restart_count", report.RestartCount)
+
+	return report
+}
+
+// forgetContainer drops every route of a removed container, including
+// routes kept while it was stopped
+func (p *DockerProvider) forgetContainer(container types.ContainerJSON) {
+	containerKey := p.discovery.GetContainerKey(container)
+
+	p.mu.Lock()
+	targets := p.proxyTargets[containerKey]
+	delete(p.proxyTargets, containerKey)
+	delete(p.pendingRemoval, containerKey)
+	delete(p.lastActivity, containerKey)
+	delete(p.idleExempt, containerKey)
+	p.mu.Unlock()
+
+	for _, target := range targets {
+		p.logger.Info("Removed proxy target",
+			"domain", target.Domain,
+			"container", target.ContainerName)
+	}
+}
+
+// expirePendingRemovals removes routes whose retry window has elapsed
+// without their container coming back
+func (p *DockerProvider) expirePendingRemovals(ctx context.Context) {
+	now := time.Now()
+
+	p.mu.Lock()
+	var removed []docker.ProxyTarget
+	for containerKey, removal := range p.pendingRemoval {
+		if now.Before(removal.deadline) {
+			continue
+		}
+		removed = append(removed, p.proxyTargets[containerKey]...)
+		if len(removal.stopped) > 0 {
+			p.proxyTargets[containerKey] = removal.stopped
+		} else {
+			delete(p.proxyTargets, containerKey)
+		}
+		delete(p.pendingRemoval, containerKey)
+	}
+	p.mu.Unlock()
+
+	if len(removed) == 0 {
+		return
+	}
+
+	for _, target := range removed {
+		p.logger.Info("Removed proxy target",
+			"domain", target.Domain,
+			"container", target.ContainerName)
+	}
+
+	p.publish(ctx)
+}
+
+// ListContainers lists the containers that can have routes: running ones,
+// and stopped ones too when their routes are kept or they start on request
+func (p *DockerProvider) ListContainers(ctx context.Context) ([]types.Container, error) {
+	if p.config.DevProxy.KeepStopped {
+		return p.monitor.GetAllContainers(ctx)
+	}
+
+	containers, err := p.monitor.GetRunningContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	autostart, err := p.monitor.GetAllContainers(ctx, "devproxy.autostart=true")
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[string]bool)
+	for _, container := range containers {
+		listed[container.ID] = true
+	}
+	for _, container := range autostart {
+		if !listed[container.ID] {
+			containers = append(containers, container)
+		}
+	}
+
+	return containers, nil
+}
+
+func (p *DockerProvider) InspectContainer(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	return p.monitor.InspectContainer(ctx, containerID)
+}
+
+// ExtractTargets returns the routes of a container in its current state
+func (p *DockerProvider) ExtractTargets(container types.ContainerJSON) []docker.ProxyTarget {
+	if container.State.Running {
+		return p.discovery.ExtractProxyTargets(container)
+	}
+	return p.stoppedTargets(container, false)
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"devproxy/internal/docker"
+)
+
+// ProbeHealth runs a target's health check once, the way Caddy's active
+// health checks do. Caddy does not report the results of its own checks,
+// only the failures of proxied requests.
+func ProbeHealth(ctx context.Context, target docker.ProxyTarget) error {
+	check := target.HealthCheck
+	if check == nil {
+		return nil
+	}
+
+	port := target.Port
+	if check.Port != 0 {
+		port = check.Port
+	}
+
+	scheme := "http"
+	if target.Scheme == docker.SchemeHTTPS {
+		scheme = "https"
+	}
+	url := scheme + "://" + net.JoinHostPort(target.ContainerIP, strconv.Itoa(port)) + check.Path
+
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	// Dev certificates are rarely verifiable from here; the route's own
+	// TLS settings still apply to proxied requests
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if !expectedStatus(check.ExpectStatus, resp.StatusCode) {
+		return fmt.Errorf("%s returned status %d", check.Path, resp.StatusCode)
+	}
+	return nil
+}
+
+// expectedStatus reports whether a status passes the health check like it
+// does in Caddy: a single digit expects a class, such as 2 for any 2xx, and
+// no expectation accepts any 2xx
+func expectedStatus(expect, status int) bool {
+	switch {
+	case expect == 0:
+		return status >= 200 && status <= 299
+	case expect < 100:
+		return status/100 == expect
+	default:
+		return status == expect
+	}
+}
@@ -0,0 +1,28 @@
+package proxy
+
+import "testing"
+
+func TestExpectedStatus(t *testing.T) {
+	tests := []struct {
+		expect, status int
+		want           bool
+	}{
+		{expect: 0, status: 200, want: true},
+		{expect: 0, status: 204, want: true},
+		{expect: 0, status: 301, want: false},
+		{expect: 0, status: 500, want: false},
+		{expect: 2, status: 200, want: true},
+		{expect: 2, status: 299, want: true},
+		{expect: 2, status: 302, want: false},
+		{expect: 4, status: 401, want: true},
+		{expect: 204, status: 204, want: true},
+		{expect: 204, status: 200, want: false},
+		{expect: 401, status: 403, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := expectedStatus(tt.expect, tt.status); got != tt.want {
+			t.Errorf("expectedStatus(%d, %d) = %v, want %v", tt.expect, tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"devproxy/internal/config"
+	"devproxy/internal/docker"
+	"devproxy/internal/hostscan"
+)
+
+// HostProvider routes dev servers found listening on the host
+type HostProvider struct {
+	scanner  *hostscan.Scanner
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	targets []docker.ProxyTarget
+	err     error
+}
+
+func NewHostProvider(cfg *config.Config, logger *slog.Logger) *HostProvider {
+	return &HostProvider{
+		scanner: &hostscan.Scanner{
+			ProcRoot:     cfg.DevProxy.HostProc,
+			Upstream:     cfg.DevProxy.HostScanUpstream,
+			DomainSuffix: cfg.DevProxy.DomainSuffix,
+			Include:      cfg.DevProxy.HostScanInclude,
+			Exclude:      cfg.DevProxy.HostScanExclude,
+		},
+		interval: cfg.DevProxy.HostScanInterval,
+		logger:   logger,
+	}
+}
+
+func (p *HostProvider) Name() string {
+	return hostscan.Source
+}
+
+// Run scans the host every interval, publishing the dev servers found
+// whenever they change
+func (p *HostProvider) Run(ctx context.Context, updates chan<- TargetSet) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	loaded := false
+	for {
+		targets, _ := p.Snapshot(ctx)
+
+		p.mu.Lock()
+		changed := !loaded || !slices.EqualFunc(targets, p.targets, sameHostTarget)
+		p.targets = targets
+		p.mu.Unlock()
+
+		if changed {
+			p.logger.Info("Host dev servers changed", "routes", len(targets))
+			publish(ctx, updates, TargetSet{Provider: p.Name(), Targets: targets})
+			loaded = true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Snapshot scans the host for listening dev servers
+func (p *HostProvider) Snapshot(ctx context.Context) ([]docker.ProxyTarget, error) {
+	targets, err := p.scanner.Scan()
+	if err != nil {
+		p.logger.Warn("Failed to scan host listeners", "proc", p.scanner.ProcRoot, "error", err)
+	}
+
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+
+	return targets, nil
+}
+
+func (p *HostProvider) Health() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.err
+}
+
+// GetHostTargets returns the routes of the dev servers found by the last
+// scan
+func (p *HostProvider) GetHostTargets() []docker.ProxyTarget {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return slices.Clone(p.targets)
+}
+
+func sameHostTarget(a, b docker.ProxyTarget) bool {
+	return a.Domain == b.Domain && a.ContainerName == b.ContainerName && a.Dial() == b.Dial()
+}
+
+// handleAPIHost lists the host dev servers, which is empty when host
+// scanning is off
+func (m *Manager) handleAPIHost(w http.ResponseWriter, r *http.Request) {
+	targets := []docker.ProxyTarget{}
+	if m.host != nil {
+		targets = append(targets, m.host.GetHostTargets()...)
+	}
+	writeJSON(w, http.StatusOK, targets)
+}
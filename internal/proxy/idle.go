@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IdleState describes when a container labelled devproxy.idle_timeout will
+// be stopped
+type IdleState struct {
+	ContainerID   string     `json:"container_id"`
+	ContainerName string     `json:"container"`
+	Timeout       string     `json:"timeout"`
+	LastActivity  time.Time  `json:"last_activity"`
+	StopsAt       time.Time  `json:"stops_at"`
+	ExemptUntil   *time.Time `json:"exempt_until,omitempty"`
+}
+
+// accessLogEntry holds the fields of a Caddy access log line that matter
+// for activity tracking
+type accessLogEntry struct {
+	Request struct {
+		Host string `json:"host"`
+		URI  string `json:"uri"`
+	} `json:"request"`
+}
+
+// ServeAccessLog receives the JSON access logs Caddy streams over TCP and
+// records request activity per container, until ctx is canceled.
+func (m *Manager) ServeAccessLog(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Receiving access logs", "addr", addr)
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go m.readAccessLog(conn)
+	}
+}
+
+func (m *Manager) readAccessLog(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry accessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			m.logger.Debug("Ignoring unreadable access log line", "error", err)
+			continue
+		}
+		for _, provider := range m.dockers {
+			provider.recordActivity(entry.Request.Host, entry.Request.URI, time.Now())
+		}
+	}
+}
+
+// recordActivity marks the containers routed for host and uri as active
+func (p *DockerProvider) recordActivity(host, uri string, at time.Time) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for containerKey, targets := range p.proxyTargets {
+		for _, target := range targets {
+			if target.Stopped || !strings.EqualFold(target.Domain, host) {
+				continue
+			}
+			if target.Path != "" && uri != target.Path && !strings.HasPrefix(uri, target.Path+"/") {
+				continue
+			}
+			p.lastActivity[containerKey] = at
+		}
+	}
+}
+
+// stopIdleContainers stops containers that received no request for their
+// idle timeout. Requests still in flight, such as open websockets, count as
+// activity even though they are not logged until they end.
+func (p *DockerProvider) stopIdleContainers(ctx context.Context) {
+	now := time.Now()
+
+	if !p.hasIdleTimeouts() {
+		return
+	}
+
+	if upstreams, err := p.caddyClient.GetUpstreams(ctx); err == nil {
+		p.mu.Lock()
+		for containerKey, targets := range p.proxyTargets {
+			for _, state := range JoinUpstreams(targets, upstreams) {
+				if state.InFlight > 0 {
+					p.lastActivity[containerKey] = now
+				}
+			}
+		}
+		p.mu.Unlock()
+	} else {
+		p.logger.Debug("Failed to get upstream status from Caddy", "error", err)
+	}
+
+	for _, state := range p.GetIdleStates() {
+		if now.Before(state.StopsAt) || (state.ExemptUntil != nil && now.Before(*state.ExemptUntil)) {
+			continue
+		}
+
+		p.logger.Info("Stopping idle container",
+			"container", state.ContainerName,
+			"idle_timeout", state.Timeout,
+			"last_activity", state.LastActivity)
+
+		// Restart the clock so the container is not stopped again while
+		// its stop is in progress, or if it comes back before the stop
+		// events arrive
+		p.mu.Lock()
+		p.lastActivity[state.ContainerID] = now
+		p.mu.Unlock()
+
+		// Stopping waits for the container's stop timeout, which must not
+		// hold up event handling; the stop events update the routes
+		go func(state IdleState) {
+			if err := p.monitor.StopContainer(ctx, state.ContainerID); err != nil {
+				p.logger.Warn("Failed to stop idle container", "container", state.ContainerName, "error", err)
+			}
+		}(state)
+	}
+}
+
+func (p *DockerProvider) hasIdleTimeouts() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, targets := range p.proxyTargets {
+		for _, target := range targets {
+			if target.IdleTimeout > 0 && !target.Stopped {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetIdleStates returns the idle shutdown state of every running container
+// with an idle timeout, soonest to stop first
+func (p *DockerProvider) GetIdleStates() []IdleState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var states []IdleState
+	for containerKey, targets := range p.proxyTargets {
+		var timeout time.Duration
+		for _, target := range targets {
+			if !target.Stopped {
+				timeout = max(timeout, target.IdleTimeout)
+			}
+		}
+		if timeout == 0 {
+			continue
+		}
+
+		lastActivity := p.lastActivity[containerKey]
+		state := IdleState{
+			ContainerID:   containerKey,
+			ContainerName: targets[0].ContainerName,
+			Timeout:       timeout.String(),
+			LastActivity:  lastActivity,
+			StopsAt:       lastActivity.Add(timeout),
+		}
+		if until, exempt := p.idleExempt[containerKey]; exempt && time.Now().Before(until) {
+			state.ExemptUntil = &until
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].StopsAt.Before(states[j].StopsAt)
+	})
+
+	return states
+}
+
+// SetIdleExempt keeps a container from being stopped for being idle until
+// the end of the day, or lifts that exemption
+func (p *DockerProvider) SetIdleExempt(containerID string, exempt bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	targets, exists := p.proxyTargets[containerID]
+	if !exists || len(targets) == 0 {
+		return false
+	}
+
+	if !exempt {
+		delete(p.idleExempt, containerID)
+		return true
+	}
+
+	now := time.Now()
+	p.idleExempt[containerID] = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	p.logger.Info("Exempted container from idle shutdown",
+		"container", targets[0].ContainerName,
+		"until", p.idleExempt[containerID])
+
+	return true
+}
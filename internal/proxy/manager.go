@@ -2,8 +2,13 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"devproxy/internal/caddy"
 	"devproxy/internal/config"
@@ -12,34 +17,86 @@ import (
 	"github.com/docker/docker/api/types"
 )
 
+// providerRetryDelay is how long the manager waits before running a failed
+// provider again
+const providerRetryDelay = 5 * time.Second
+
+// providerStartTimeout is how long the manager waits for every provider's
+// first target set before updating Caddy without the missing ones
+const providerStartTimeout = 30 * time.Second
+
 type Manager struct {
-	dockerMonitor   *docker.Monitor
-	discovery       *docker.Discovery
+	config          *config.Config
 	configGenerator *caddy.ConfigGenerator
 	caddyClient     *caddy.Client
 	logger          *slog.Logger
 
+	docker        *DockerProvider   // provider of the first Docker host
+	dockers       []*DockerProvider // providers of every Docker host
+	static        *StaticProvider   // nil without a routes file
+	host          *HostProvider     // nil unless host scanning is enabled
+	registrations *RegistrationProvider
+	providers     []Provider // in precedence order
+
 	mu             sync.RWMutex
-	proxyTargets   map[string][]docker.ProxyTarget // container ID -> targets
+	sets           map[string]TargetSet // provider name -> latest set
+	updatedAt      map[string]time.Time // provider name -> time of latest set
+	failures       map[string]error     // provider name -> error ending its run
 	lastConfigHash string
+	lastConflicts  map[string]string // domain and path -> claims last logged
 }
 
 func NewManager(cfg *config.Config, logger *slog.Logger) (*Manager, error) {
-	monitor, err := docker.NewMonitor(logger)
+	caddyClient := caddy.NewClient(cfg.DevProxy.CaddyAdminURL, logger)
+
+	endpoints, err := docker.LoadEndpoints(cfg.DevProxy.DockerEndpointsFile, docker.Endpoint{
+		Upstream:      cfg.DevProxy.UpstreamMode,
+		PublishedHost: cfg.DevProxy.PublishedHost,
+		Networks:      cfg.DevProxy.CaddyNetworks,
+		IPFamily:      cfg.DevProxy.IPFamily,
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to load docker endpoints: %w", err)
 	}
 
-	caddyClient := caddy.NewClient(cfg.DevProxy.CaddyAdminURL, logger)
-
-	return &Manager{
-		dockerMonitor:   monitor,
-		discovery:       docker.NewDiscovery(),
-		configGenerator: caddy.NewConfigGenerator(),
+	m := &Manager{
+		config:          cfg,
+		configGenerator: caddy.NewConfigGenerator(cfg),
 		caddyClient:     caddyClient,
 		logger:          logger,
-		proxyTargets:    make(map[string][]docker.ProxyTarget),
-	}, nil
+		registrations:   NewRegistrationProvider(cfg, logger),
+		sets:            make(map[string]TargetSet),
+		updatedAt:       make(map[string]time.Time),
+		failures:        make(map[string]error),
+	}
+
+	for _, endpoint := range endpoints {
+		provider, err := NewDockerProvider(cfg, endpoint, caddyClient, logger)
+		if err != nil {
+			return nil, err
+		}
+		m.dockers = append(m.dockers, provider)
+		m.AddProvider(provider)
+	}
+	m.docker = m.dockers[0]
+
+	m.AddProvider(m.registrations)
+	if cfg.DevProxy.RoutesFile != "" {
+		m.static = NewStaticProvider(cfg.DevProxy.RoutesFile, logger)
+		m.AddProvider(m.static)
+	}
+	if cfg.DevProxy.HostScan {
+		m.host = NewHostProvider(cfg, logger)
+		m.AddProvider(m.host)
+	}
+
+	return m, nil
+}
+
+// AddProvider adds a source of targets, ordered by the configured provider
+// precedence. Providers must be added before Start.
+func (m *Manager) AddProvider(provider Provider) {
+	m.providers = orderProviders(append(m.providers, provider), m.config.DevProxy.ProviderPrecedence)
 }
 
 func (m *Manager) Start(ctx context.Context) error {
@@ -49,25 +106,46 @@ func (m *Manager) Start(ctx context.Context) error {
 		return err
 	}
 
-	// Initialize with existing containers
-	if err := m.syncExistingContainers(ctx); err != nil {
-		m.logger.Error("Failed to sync existing containers", "error", err)
-		return err
+	updates := make(chan TargetSet)
+	var names []string
+	for _, provider := range m.providers {
+		names = append(names, provider.Name())
+		go m.runProvider(ctx, provider, updates)
 	}
 
-	// Start monitoring Docker events
-	eventsChan := make(chan docker.ContainerEvent, 10)
-	if err := m.dockerMonitor.Start(ctx, eventsChan); err != nil {
-		return err
-	}
+	m.logger.Info("DevProxy manager started", "providers", strings.Join(names, ","))
 
-	m.logger.Info("DevProxy manager started, monitoring Docker containers...")
+	// Caddy is only updated once every provider has published its first
+	// set, so a restart does not drop the routes of slower providers
+	startTimeout := time.NewTimer(providerStartTimeout)
+	defer startTimeout.Stop()
+	started := false
+	var pending []chan<- error
 
-	// Process events
+	// Process target sets
 	for {
 		select {
-		case event := <-eventsChan:
-			m.handleContainerEvent(ctx, event)
+		case set := <-updates:
+			if set.Applied != nil {
+				pending = append(pending, set.Applied)
+			}
+			m.storeTargetSet(set)
+
+			if !started && len(m.waitingProviders()) > 0 {
+				continue
+			}
+			started = true
+			m.applyTargetSets(ctx, pending)
+			pending = nil
+		case <-startTimeout.C:
+			if started {
+				continue
+			}
+			m.logger.Warn("Providers did not publish their targets in time, updating Caddy without them",
+				"providers", strings.Join(m.waitingProviders(), ","))
+			started = true
+			m.applyTargetSets(ctx, pending)
+			pending = nil
 		case <-ctx.Done():
 			m.logger.Info("DevProxy manager stopping...")
 			return nil
@@ -75,91 +153,90 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 }
 
-func (m *Manager) syncExistingContainers(ctx context.Context) error {
-	containers, err := m.dockerMonitor.GetRunningContainers(ctx)
-	if err != nil {
-		return err
-	}
-
-	m.logger.Info("Syncing existing containers", "count", len(containers))
+// waitingProviders returns the providers that have neither published a
+// target set nor failed yet
+func (m *Manager) waitingProviders() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	for _, container := range containers {
-		containerInfo, err := m.dockerMonitor.InspectContainer(ctx, container.ID)
-		if err != nil {
-			m.logger.Warn("Failed to inspect container", "container_id", container.ID, "error", err)
-			continue
+	var waiting []string
+	for _, provider := range m.providers {
+		_, published := m.sets[provider.Name()]
+		_, failed := m.failures[provider.Name()]
+		if !published && !failed {
+			waiting = append(waiting, provider.Name())
 		}
-
-		m.addContainer(ctx, containerInfo)
 	}
-
-	return m.updateCaddyConfig(ctx)
+	return waiting
 }
 
-func (m *Manager) handleContainerEvent(ctx context.Context, event docker.ContainerEvent) {
-	switch event.Action {
-	case "start":
-		m.addContainer(ctx, event.Container)
-	case "stop", "die":
-		m.removeContainer(ctx, event.Container)
-	default:
-		return
-	}
-
-	if err := m.updateCaddyConfig(ctx); err != nil {
-		m.logger.Error("Failed to update Caddy config", "error", err)
-	}
-}
-
-func (m *Manager) addContainer(ctx context.Context, container types.ContainerJSON) {
-	targets := m.discovery.ExtractProxyTargets(container)
-	if len(targets) == 0 {
-		return
-	}
+// runProvider runs a provider until ctx is canceled, running it again
+// after a delay whenever it fails. The provider's routes stay in place
+// while it is down.
+func (m *Manager) runProvider(ctx context.Context, provider Provider, updates chan<- TargetSet) {
+	for {
+		err := provider.Run(ctx, updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("provider %s stopped", provider.Name())
+		}
 
-	containerKey := m.discovery.GetContainerKey(container)
+		m.logger.Error("Provider failed", "provider", provider.Name(), "error", err)
+		m.mu.Lock()
+		m.failures[provider.Name()] = err
+		m.mu.Unlock()
 
-	m.mu.Lock()
-	m.proxyTargets[containerKey] = targets
-	m.mu.Unlock()
+		select {
+		case <-time.After(providerRetryDelay):
+		case <-ctx.Done():
+			return
+		}
 
-	for _, target := range targets {
-		m.logger.Info("Added proxy target",
-			"domain", target.Domain,
-			"container_ip", target.ContainerIP,
-			"port", target.Port,
-			"container", container.Name)
+		m.mu.Lock()
+		delete(m.failures, provider.Name())
+		m.mu.Unlock()
 	}
 }
 
-func (m *Manager) removeContainer(ctx context.Context, container types.ContainerJSON) {
-	containerKey := m.discovery.GetContainerKey(container)
+// storeTargetSet replaces a provider's targets
+func (m *Manager) storeTargetSet(set TargetSet) {
+	set.Applied = nil
 
 	m.mu.Lock()
-	targets, exists := m.proxyTargets[containerKey]
-	if exists {
-		delete(m.proxyTargets, containerKey)
-	}
+	m.sets[set.Provider] = set
+	m.updatedAt[set.Provider] = time.Now()
 	m.mu.Unlock()
+}
 
-	if exists {
-		for _, target := range targets {
-			m.logger.Info("Removed proxy target",
-				"domain", target.Domain,
-				"container", container.Name)
-		}
+// applyTargetSets updates Caddy with the stored target sets, reporting the
+// result to the providers waiting for it
+func (m *Manager) applyTargetSets(ctx context.Context, applied []chan<- error) {
+	err := m.updateCaddyConfig(ctx)
+	if err != nil {
+		m.logger.Error("Failed to update Caddy config", "error", err)
+	}
+	for _, done := range applied {
+		done <- err
 	}
 }
 
 func (m *Manager) updateCaddyConfig(ctx context.Context) error {
 	m.mu.RLock()
-	var allTargets []docker.ProxyTarget
-	for _, targets := range m.proxyTargets {
-		allTargets = append(allTargets, targets...)
+	var sets []TargetSet
+	for _, provider := range m.providers {
+		if set, found := m.sets[provider.Name()]; found {
+			sets = append(sets, set)
+		}
 	}
 	m.mu.RUnlock()
 
-	config, err := m.configGenerator.GenerateConfig(allTargets)
+	allTargets, conflicts := MergeTargets(sets)
+
+	m.logNewConflicts(conflicts)
+
+	config, err := m.buildConfig(ctx, allTargets, m.findServiceDial)
 	if err != nil {
 		return err
 	}
@@ -185,30 +262,273 @@ func (m *Manager) updateCaddyConfig(ctx context.Context) error {
 	return nil
 }
 
-func (m *Manager) hashConfig(config []byte) string {
-	// Simple hash for change detection
-	return string(config)
+// logNewConflicts warns about conflicts that are new or whose claims
+// changed since the previous update, so unchanged ones are logged once
+func (m *Manager) logNewConflicts(conflicts []docker.Conflict) {
+	current := make(map[string]string)
+	for _, conflict := range conflicts {
+		var ignored []string
+		for _, loser := range conflict.Losers {
+			ignored = append(ignored, loser.ContainerName)
+		}
+
+		route := conflict.Domain + conflict.Path
+		claims := conflict.Winner.ContainerName + " over " + strings.Join(ignored, ",")
+		current[route] = claims
+		if m.lastConflicts[route] == claims {
+			continue
+		}
+
+		m.logger.Warn("Domain claimed by several targets",
+			"domain", conflict.Domain,
+			"path", conflict.Path,
+			"routed_to", conflict.Winner.ContainerName,
+			"ignored", strings.Join(ignored, ","))
+	}
+	m.lastConflicts = current
 }
 
-func (m *Manager) GetProxyTargets() map[string][]docker.ProxyTarget {
+// serviceLookup returns the address of a compose service's running
+// container, on the given port or else its detected one
+type serviceLookup func(ctx context.Context, project, service string, port int) (string, error)
+
+// findServiceDial looks up a compose service on every Docker host
+func (m *Manager) findServiceDial(ctx context.Context, project, service string, port int) (string, error) {
+	var err error
+	for _, provider := range m.dockers {
+		var container types.ContainerJSON
+		container, err = provider.monitor.FindComposeService(ctx, project, service)
+		if err == nil {
+			return provider.discovery.ServiceDial(container, port), nil
+		}
+	}
+	return "", err
+}
+
+// buildConfig turns proxy targets into a Caddy configuration. It is shared by
+// live updates and dry-run rendering so both produce the same output.
+func (m *Manager) buildConfig(ctx context.Context, targets []docker.ProxyTarget, lookup serviceLookup) (*caddy.CaddyConfig, error) {
+	return m.configGenerator.GenerateConfig(m.resolveForwardAuth(ctx, targets, lookup))
+}
+
+// resolveForwardAuth fills in the dial address of forward auth services
+// referenced by compose service name. Services that cannot be found are left
+// unresolved, which makes their routes reject requests.
+func (m *Manager) resolveForwardAuth(ctx context.Context, targets []docker.ProxyTarget, lookup serviceLookup) []docker.ProxyTarget {
+	resolved := make([]docker.ProxyTarget, len(targets))
+	for i, target := range targets {
+		if target.ForwardAuth != nil && target.ForwardAuth.Service != "" && target.ForwardAuth.Dial == "" {
+			auth := *target.ForwardAuth
+
+			dial, err := lookup(ctx, auth.Project, auth.Service, auth.Port)
+			if err != nil {
+				m.logger.Warn("Failed to resolve forward auth service",
+					"domain", target.Domain,
+					"service", auth.Service,
+					"project", auth.Project,
+					"error", err)
+			} else {
+				auth.Dial = dial
+			}
+
+			target.ForwardAuth = &auth
+		}
+		resolved[i] = target
+	}
+
+	return resolved
+}
+
+// Render generates the Caddy configuration for the given containers, taken
+// as those of the first Docker host, and the current targets of the
+// providers other than Docker, without touching the manager's state or
+// Caddy. Forward auth services are looked up among the same containers.
+func (m *Manager) Render(ctx context.Context, containers []types.ContainerJSON) (*caddy.CaddyConfig, error) {
+	var containerTargets []docker.ProxyTarget
+	for _, container := range containers {
+		containerTargets = append(containerTargets, m.docker.ExtractTargets(container)...)
+	}
+
+	sets, err := m.snapshotSets(ctx, containerTargets, true)
+	if err != nil {
+		return nil, err
+	}
+	allTargets, _ := MergeTargets(sets)
+
+	lookup := func(ctx context.Context, project, service string, port int) (string, error) {
+		for _, container := range containers {
+			labels := container.Config.Labels
+			if container.State.Running && labels["com.docker.compose.project"] == project && labels["com.docker.compose.service"] == service {
+				return m.docker.discovery.ServiceDial(container, port), nil
+			}
+		}
+		return "", fmt.Errorf("no running container for service %s in project %s", service, project)
+	}
+
+	return m.buildConfig(ctx, allTargets, lookup)
+}
+
+// RenderCurrent renders the configuration for the containers currently
+// running in Docker.
+func (m *Manager) RenderCurrent(ctx context.Context) (*caddy.CaddyConfig, error) {
+	targets, err := m.CurrentTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.buildConfig(ctx, targets, m.findServiceDial)
+}
+
+// CurrentTargets takes a snapshot of every provider and merges them,
+// independently of the manager's own state.
+func (m *Manager) CurrentTargets(ctx context.Context) ([]docker.ProxyTarget, error) {
+	sets, err := m.snapshotSets(ctx, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, _ := MergeTargets(sets)
+	return targets, nil
+}
+
+// CurrentConflicts returns the conflicts among the providers' current
+// targets, including routes hidden by a provider of higher precedence
+func (m *Manager) CurrentConflicts(ctx context.Context) ([]docker.Conflict, error) {
+	sets, err := m.snapshotSets(ctx, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	_, conflicts := MergeTargets(sets)
+	return conflicts, nil
+}
+
+// snapshotSets takes a snapshot of every provider in precedence order.
+// With dumped set, containerTargets stand in for the first Docker host and
+// the other hosts are left out. Failing providers are logged and left out;
+// it is an error only when no Docker host could be listed.
+func (m *Manager) snapshotSets(ctx context.Context, containerTargets []docker.ProxyTarget, dumped bool) ([]TargetSet, error) {
+	var sets []TargetSet
+	var dockerErr error
+	dockerListed := false
+	for _, provider := range m.providers {
+		_, isDocker := provider.(*DockerProvider)
+
+		var targets []docker.ProxyTarget
+		var err error
+		switch {
+		case dumped && provider == Provider(m.docker):
+			targets = containerTargets
+		case dumped && isDocker:
+			continue
+		default:
+			targets, err = provider.Snapshot(ctx)
+		}
+
+		if err != nil {
+			m.logger.Warn("Failed to get provider targets", "provider", provider.Name(), "error", err)
+			if isDocker {
+				dockerErr = err
+			}
+			continue
+		}
+		if isDocker {
+			dockerListed = true
+		}
+		sets = append(sets, TargetSet{Provider: provider.Name(), Targets: targets})
+	}
+
+	if !dockerListed {
+		return nil, dockerErr
+	}
+	return sets, nil
+}
+
+// ProviderStatuses reports the health of every provider in precedence order
+func (m *Manager) ProviderStatuses() []ProviderStatus {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	result := make(map[string][]docker.ProxyTarget)
-	for k, v := range m.proxyTargets {
-		result[k] = v
+	statuses := make([]ProviderStatus, 0, len(m.providers))
+	for i, provider := range m.providers {
+		status := ProviderStatus{
+			Name:       provider.Name(),
+			Precedence: i + 1,
+			Targets:    len(m.sets[provider.Name()].Targets),
+		}
+
+		err := m.failures[provider.Name()]
+		if err == nil {
+			err = provider.Health()
+		}
+		status.Healthy = err == nil
+		if err != nil {
+			status.Error = err.Error()
+		}
+
+		if updatedAt, found := m.updatedAt[provider.Name()]; found {
+			status.UpdatedAt = &updatedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// LoadStaticTargets reads the static routes file, if one is configured.
+// Invalid routes are left out.
+func (m *Manager) LoadStaticTargets() []docker.ProxyTarget {
+	if m.static == nil {
+		return nil
+	}
+
+	targets, _ := m.static.Snapshot(context.Background())
+	return targets
+}
+
+// DockerProviders returns the provider of every Docker host
+func (m *Manager) DockerProviders() []*DockerProvider {
+	return m.dockers
+}
+
+// GetIdleStates returns the idle shutdown state of containers with an idle
+// timeout on every Docker host, soonest to stop first
+func (m *Manager) GetIdleStates() []IdleState {
+	var states []IdleState
+	for _, provider := range m.dockers {
+		states = append(states, provider.GetIdleStates()...)
 	}
-	return result
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].StopsAt.Before(states[j].StopsAt)
+	})
+
+	return states
 }
 
-func (m *Manager) GetRunningContainers(ctx context.Context) ([]types.Container, error) {
-	return m.dockerMonitor.GetRunningContainers(ctx)
+// SetIdleExempt keeps a container from idle shutdown for the rest of the
+// day, or lifts that exemption
+func (m *Manager) SetIdleExempt(containerID string, exempt bool) bool {
+	for _, provider := range m.dockers {
+		if provider.SetIdleExempt(containerID, exempt) {
+			return true
+		}
+	}
+	return false
 }
 
-func (m *Manager) InspectContainer(ctx context.Context, containerID string) (types.ContainerJSON, error) {
-	return m.dockerMonitor.InspectContainer(ctx, containerID)
+// LiveConfig returns the configuration Caddy is currently running, as raw
+// JSON so that fields devproxy does not model show up in diffs.
+func (m *Manager) LiveConfig(ctx context.Context) (json.RawMessage, error) {
+	return m.caddyClient.GetConfig(ctx)
+}
+
+func (m *Manager) hashConfig(config []byte) string {
+	// Simple hash for change detection
+	return string(config)
 }
 
-func (m *Manager) GetDiscovery() *docker.Discovery {
-	return m.discovery
+// GetUpstreams returns Caddy's status of every upstream
+func (m *Manager) GetUpstreams(ctx context.Context) ([]caddy.UpstreamStatus, error) {
+	return m.caddyClient.GetUpstreams(ctx)
 }
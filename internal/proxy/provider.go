@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"devproxy/internal/docker"
+)
+
+// Provider is a source of proxy targets. The manager runs every provider
+// and merges the target sets they publish into Caddy's configuration.
+type Provider interface {
+	// Name identifies the provider in logs, status and precedence settings
+	Name() string
+
+	// Run publishes the provider's complete set of targets, first a
+	// snapshot and then again whenever it changes, until ctx is canceled
+	Run(ctx context.Context, updates chan<- TargetSet) error
+
+	// Snapshot returns the provider's current targets without running it,
+	// for dry-run rendering and the dashboard
+	Snapshot(ctx context.Context) ([]docker.ProxyTarget, error)
+
+	// Health returns the problem keeping the provider from discovering
+	// targets, or nil when it is working
+	Health() error
+}
+
+// TargetSet is the complete set of targets of a provider at one point in
+// time. It replaces the provider's previous set.
+type TargetSet struct {
+	Provider string
+	Targets  []docker.ProxyTarget
+
+	// Applied, when set, receives the result of updating Caddy with the set
+	Applied chan<- error
+}
+
+// ProviderStatus reports the health of a provider
+type ProviderStatus struct {
+	Name       string     `json:"name"`
+	Precedence int        `json:"precedence"`
+	Healthy    bool       `json:"healthy"`
+	Error      string     `json:"error,omitempty"`
+	Targets    int        `json:"targets"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// publish sends a target set to the manager, giving up when ctx is canceled
+func publish(ctx context.Context, updates chan<- TargetSet, set TargetSet) {
+	select {
+	case updates <- set:
+	case <-ctx.Done():
+	}
+}
+
+// orderProviders sorts providers by the precedence list; providers missing
+// from it keep their order after the listed ones. A provider named
+// "kind:instance", such as "docker:vm", also matches an entry for its kind.
+func orderProviders(providers []Provider, precedence []string) []Provider {
+	rank := func(p Provider) int {
+		if i := slices.Index(precedence, p.Name()); i >= 0 {
+			return i
+		}
+		kind, _, _ := strings.Cut(p.Name(), ":")
+		if i := slices.Index(precedence, kind); i >= 0 {
+			return i
+		}
+		return len(precedence)
+	}
+
+	ordered := slices.Clone(providers)
+	slices.SortStableFunc(ordered, func(a, b Provider) int {
+		return rank(a) - rank(b)
+	})
+	return ordered
+}
+
+// MergeTargets combines target sets given in precedence order. A route
+// claimed by a provider hides the claims of every provider after it, and
+// those hidden claims are reported as conflicts. Claims within one provider
+// are settled by docker.ResolveConflicts as usual.
+func MergeTargets(sets []TargetSet) ([]docker.ProxyTarget, []docker.Conflict) {
+	type route struct{ domain, path string }
+
+	claimed := make(map[route]bool)
+	hidden := make(map[route][]docker.ProxyTarget)
+	var merged []docker.ProxyTarget
+	for _, set := range sets {
+		var kept []docker.ProxyTarget
+		for _, target := range set.Targets {
+			key := route{target.Domain, target.Path}
+			if claimed[key] {
+				hidden[key] = append(hidden[key], target)
+				continue
+			}
+			kept = append(kept, target)
+		}
+
+		for _, target := range kept {
+			claimed[route{target.Domain, target.Path}] = true
+		}
+		merged = append(merged, kept...)
+	}
+
+	winners, conflicts := docker.ResolveConflicts(merged)
+	if len(hidden) == 0 {
+		return merged, conflicts
+	}
+
+	// Keep the conflicts in routing order, adding the hidden claims
+	existing := make(map[route]docker.Conflict)
+	for _, conflict := range conflicts {
+		existing[route{conflict.Domain, conflict.Path}] = conflict
+	}
+
+	var all []docker.Conflict
+	for _, winner := range winners {
+		key := route{winner.Domain, winner.Path}
+		conflict, found := existing[key]
+		if !found {
+			if len(hidden[key]) == 0 {
+				continue
+			}
+			conflict = docker.Conflict{Domain: winner.Domain, Path: winner.Path, Winner: winner}
+		}
+		conflict.Losers = append(conflict.Losers, hidden[key]...)
+		all = append(all, conflict)
+	}
+
+	return merged, all
+}
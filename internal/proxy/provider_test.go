@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"slices"
+	"testing"
+
+	"devproxy/internal/docker"
+)
+
+func target(domain, path, name string, priority int) docker.ProxyTarget {
+	return docker.ProxyTarget{Domain: domain, Path: path, ContainerName: name, Priority: priority}
+}
+
+// names lists targets as "domain/path=container" for comparison
+func names(targets []docker.ProxyTarget) []string {
+	var result []string
+	for _, t := range targets {
+		result = append(result, t.Domain+t.Path+"="+t.ContainerName)
+	}
+	return result
+}
+
+func TestMergeTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		sets      []TargetSet
+		merged    []string
+		conflicts map[string][]string // route -> winner followed by losers
+	}{
+		{
+			name: "disjoint routes are all kept",
+			sets: []TargetSet{
+				{Provider: "api", Targets: []docker.ProxyTarget{target("a.localhost", "", "api-a", 0)}},
+				{Provider: "docker", Targets: []docker.ProxyTarget{target("b.localhost", "", "web", 0)}},
+			},
+			merged: []string{"a.localhost=api-a", "b.localhost=web"},
+		},
+		{
+			name: "earlier provider hides later claims whatever their priority",
+			sets: []TargetSet{
+				{Provider: "static", Targets: []docker.ProxyTarget{target("app.localhost", "", "static", 0)}},
+				{Provider: "docker", Targets: []docker.ProxyTarget{target("app.localhost", "", "web", 100)}},
+			},
+			merged:    []string{"app.localhost=static"},
+			conflicts: map[string][]string{"app.localhost": {"static", "web"}},
+		},
+		{
+			name: "other paths of the domain are not hidden",
+			sets: []TargetSet{
+				{Provider: "static", Targets: []docker.ProxyTarget{target("app.localhost", "/api", "static", 0)}},
+				{Provider: "docker", Targets: []docker.ProxyTarget{target("app.localhost", "", "web", 0)}},
+			},
+			merged: []string{"app.localhost/api=static", "app.localhost=web"},
+		},
+		{
+			name: "claims within a provider are settled by priority",
+			sets: []TargetSet{
+				{Provider: "docker", Targets: []docker.ProxyTarget{
+					target("app.localhost", "", "low", 0),
+					target("app.localhost", "", "high", 10),
+				}},
+			},
+			merged:    []string{"app.localhost=low", "app.localhost=high"},
+			conflicts: map[string][]string{"app.localhost": {"high", "low"}},
+		},
+		{
+			name: "hidden claims join the conflicts within the winning provider",
+			sets: []TargetSet{
+				{Provider: "static", Targets: []docker.ProxyTarget{
+					target("app.localhost", "", "one", 0),
+					target("app.localhost", "", "two", 5),
+				}},
+				{Provider: "docker", Targets: []docker.ProxyTarget{target("app.localhost", "", "web", 100)}},
+				{Provider: "host", Targets: []docker.ProxyTarget{target("app.localhost", "", "vite", 0)}},
+			},
+			merged:    []string{"app.localhost=one", "app.localhost=two"},
+			conflicts: map[string][]string{"app.localhost": {"two", "one", "web", "vite"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := MergeTargets(tt.sets)
+
+			if got := names(merged); !slices.Equal(got, tt.merged) {
+				t.Errorf("merged = %v, want %v", got, tt.merged)
+			}
+
+			if len(conflicts) != len(tt.conflicts) {
+				t.Fatalf("got %d conflicts, want %d: %+v", len(conflicts), len(tt.conflicts), conflicts)
+			}
+			for _, conflict := range conflicts {
+				want, found := tt.conflicts[conflict.Domain+conflict.Path]
+				if !found {
+					t.Errorf("unexpected conflict on %s%s", conflict.Domain, conflict.Path)
+					continue
+				}
+				got := []string{conflict.Winner.ContainerName}
+				for _, loser := range conflict.Losers {
+					got = append(got, loser.ContainerName)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("conflict on %s%s = %v, want %v", conflict.Domain, conflict.Path, got, want)
+				}
+			}
+		})
+	}
+}
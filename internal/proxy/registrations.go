@@ -0,0 +1,325 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"devproxy/internal/config"
+	"devproxy/internal/docker"
+	"devproxy/internal/static"
+)
+
+// SourceAPI tags targets registered through the manager's HTTP API
+const SourceAPI = "api"
+
+// defaultRegistrationTTL applies to registrations that do not set a TTL
+const defaultRegistrationTTL = time.Minute
+
+// RegistrationRequest registers a route for as long as it is renewed. The
+// route fields are those of the static routes file.
+type RegistrationRequest struct {
+	static.Route
+	TTL string `json:"ttl,omitempty"`
+}
+
+// Registration is a route registered through the API
+type Registration struct {
+	ID        string    `json:"id"`
+	Domain    string    `json:"domain"`
+	Path      string    `json:"path,omitempty"`
+	Upstream  string    `json:"upstream"`
+	TTL       string    `json:"ttl"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	target docker.ProxyTarget
+	ttl    time.Duration
+}
+
+// RegistrationProvider serves routes registered through the manager's HTTP
+// API, which live until they are not renewed within their TTL
+type RegistrationProvider struct {
+	config *config.Config
+	logger *slog.Logger
+
+	mu            sync.RWMutex
+	registrations map[string]*Registration // registration ID -> route
+	changes       chan chan<- error
+}
+
+func NewRegistrationProvider(cfg *config.Config, logger *slog.Logger) *RegistrationProvider {
+	return &RegistrationProvider{
+		config:        cfg,
+		logger:        logger,
+		registrations: make(map[string]*Registration),
+		changes:       make(chan chan<- error),
+	}
+}
+
+func (p *RegistrationProvider) Name() string {
+	return SourceAPI
+}
+
+// Run publishes the registered routes after every change and expiry until
+// ctx is canceled
+func (p *RegistrationProvider) Run(ctx context.Context, updates chan<- TargetSet) error {
+	publish(ctx, updates, TargetSet{Provider: p.Name()})
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case applied := <-p.changes:
+			targets, _ := p.Snapshot(ctx)
+			publish(ctx, updates, TargetSet{Provider: p.Name(), Targets: targets, Applied: applied})
+		case <-ticker.C:
+			if p.expireRegistrations() {
+				targets, _ := p.Snapshot(ctx)
+				publish(ctx, updates, TargetSet{Provider: p.Name(), Targets: targets})
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Snapshot returns the routes of the current registrations
+func (p *RegistrationProvider) Snapshot(ctx context.Context) ([]docker.ProxyTarget, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var targets []docker.ProxyTarget
+	for _, registration := range p.registrations {
+		targets = append(targets, registration.target)
+	}
+	return targets, nil
+}
+
+// Health is always nil, registrations cannot fail once accepted
+func (p *RegistrationProvider) Health() error {
+	return nil
+}
+
+// handleAPIRoutes lists registered routes
+func (p *RegistrationProvider) handleAPIRoutes(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	registrations := make([]Registration, 0, len(p.registrations))
+	for _, registration := range p.registrations {
+		registrations = append(registrations, *registration)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].ExpiresAt.Before(registrations[j].ExpiresAt)
+	})
+
+	writeJSON(w, http.StatusOK, registrations)
+}
+
+// handleAPIRegister registers a route and applies it before answering, so
+// the caller can use it right away
+func (p *RegistrationProvider) handleAPIRegister(w http.ResponseWriter, r *http.Request) {
+	var request RegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := request.Target()
+	if err != nil {
+		http.Error(w, "Invalid route: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ttl, err := parseTTL(request.TTL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := newRegistrationID()
+	if err != nil {
+		http.Error(w, "Failed to generate an ID", http.StatusInternalServerError)
+		return
+	}
+
+	target.Source = SourceAPI
+	target.ContainerName = "api:" + id
+	registration := &Registration{
+		ID:        id,
+		Domain:    target.Domain,
+		Path:      target.Path,
+		Upstream:  request.Upstream,
+		TTL:       ttl.String(),
+		ExpiresAt: time.Now().Add(ttl),
+		target:    target,
+		ttl:       ttl,
+	}
+
+	p.mu.Lock()
+	p.registrations[id] = registration
+	result := *registration
+	p.mu.Unlock()
+
+	p.logger.Info("Registered route",
+		"id", id,
+		"domain", target.Domain,
+		"upstream", request.Upstream,
+		"ttl", ttl)
+
+	if err := p.applyChange(r.Context()); err != nil {
+		http.Error(w, "Route registered but Caddy was not updated: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// handleAPIHeartbeat renews a registration for another TTL
+func (p *RegistrationProvider) handleAPIHeartbeat(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	registration, exists := p.registrations[r.PathValue("id")]
+	if exists {
+		registration.ExpiresAt = time.Now().Add(registration.ttl)
+	}
+	var result Registration
+	if exists {
+		result = *registration
+	}
+	p.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "Unknown or expired registration", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleAPIUnregister removes a registration
+func (p *RegistrationProvider) handleAPIUnregister(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	p.mu.Lock()
+	_, exists := p.registrations[id]
+	delete(p.registrations, id)
+	p.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "Unknown or expired registration", http.StatusNotFound)
+		return
+	}
+
+	p.logger.Info("Unregistered route", "id", id)
+
+	if err := p.applyChange(r.Context()); err != nil {
+		http.Error(w, "Route removed but Caddy was not updated: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// expireRegistrations removes registrations that were not renewed in time,
+// reporting whether any were
+func (p *RegistrationProvider) expireRegistrations() bool {
+	now := time.Now()
+
+	p.mu.Lock()
+	var expired []*Registration
+	for id, registration := range p.registrations {
+		if now.After(registration.ExpiresAt) {
+			expired = append(expired, registration)
+			delete(p.registrations, id)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, registration := range expired {
+		p.logger.Info("Registration expired",
+			"id", registration.ID,
+			"domain", registration.Domain)
+	}
+
+	return len(expired) > 0
+}
+
+// applyChange publishes the registrations and waits until Caddy has been
+// updated with them
+func (p *RegistrationProvider) applyChange(ctx context.Context) error {
+	done := make(chan error, 1)
+
+	select {
+	case p.changes <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// requireToken rejects requests without the API token. The API is disabled
+// when no token is configured.
+func (p *RegistrationProvider) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := p.config.DevProxy.APIToken
+		if token == "" {
+			http.Error(w, "Route registration is disabled, set DEVPROXY_API_TOKEN to enable it", http.StatusForbidden)
+			return
+		}
+
+		provided, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="devproxy"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func parseTTL(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultRegistrationTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q: %w", value, err)
+	}
+	if ttl < time.Second {
+		return 0, errors.New("ttl must be at least 1s")
+	}
+	return ttl, nil
+}
+
+func newRegistrationID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
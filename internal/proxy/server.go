@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Serve runs the manager's HTTP server, which Caddy hands requests to when
+// the manager has to act on them, until ctx is canceled.
+func (m *Manager) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/autostart", m.handleAutostart)
+	mux.HandleFunc("GET /api/idle", m.handleAPIIdle)
+	mux.HandleFunc("POST /api/idle/{id}/exempt", m.handleAPIIdleExempt)
+	mux.HandleFunc("DELETE /api/idle/{id}/exempt", m.handleAPIIdleExempt)
+	mux.HandleFunc("GET /api/host", m.handleAPIHost)
+	mux.HandleFunc("GET /api/providers", m.handleAPIProviders)
+
+	api := m.registrations
+	mux.HandleFunc("GET /api/routes", api.requireToken(api.handleAPIRoutes))
+	mux.HandleFunc("POST /api/routes", api.requireToken(api.handleAPIRegister))
+	mux.HandleFunc("POST /api/routes/{id}/heartbeat", api.requireToken(api.handleAPIHeartbeat))
+	mux.HandleFunc("DELETE /api/routes/{id}", api.requireToken(api.handleAPIUnregister))
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	m.logger.Info("Starting manager server", "addr", addr)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Manager) handleAPIIdle(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.GetIdleStates())
+}
+
+// handleAPIIdleExempt keeps a container running for the rest of the day
+// (POST) or lets it be stopped when idle again (DELETE)
+func (m *Manager) handleAPIIdleExempt(w http.ResponseWriter, r *http.Request) {
+	if !m.SetIdleExempt(r.PathValue("id"), r.Method == http.MethodPost) {
+		http.Error(w, "Unknown container", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Manager) handleAPIProviders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.ProviderStatuses())
+}
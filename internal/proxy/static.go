@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"devproxy/internal/docker"
+	"devproxy/internal/static"
+)
+
+// StaticProvider serves the routes of the static routes file, reloading
+// them when the file changes
+type StaticProvider struct {
+	path   string
+	logger *slog.Logger
+
+	mu  sync.RWMutex
+	err error
+}
+
+func NewStaticProvider(path string, logger *slog.Logger) *StaticProvider {
+	return &StaticProvider{
+		path:   path,
+		logger: logger,
+	}
+}
+
+func (p *StaticProvider) Name() string {
+	return static.Source
+}
+
+// Run publishes the file's routes, and again whenever its modification time
+// changes, logging problems with the file. A file that disappears removes
+// its routes.
+func (p *StaticProvider) Run(ctx context.Context, updates chan<- TargetSet) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	var modTime time.Time
+	loaded := false
+	for {
+		var current time.Time
+		if info, err := os.Stat(p.path); err == nil {
+			current = info.ModTime()
+		}
+
+		if !loaded || !current.Equal(modTime) {
+			targets, err := static.Load(p.path)
+			if err != nil {
+				p.logger.Warn("Problem with static routes file", "file", p.path, "error", err)
+			}
+			p.mu.Lock()
+			p.err = err
+			p.mu.Unlock()
+
+			p.logger.Info("Loaded static routes", "file", p.path, "routes", len(targets))
+			publish(ctx, updates, TargetSet{Provider: p.Name(), Targets: targets})
+			modTime = current
+			loaded = true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Snapshot reads the routes file. Invalid routes are left out; Run reports
+// them when the file changes.
+func (p *StaticProvider) Snapshot(ctx context.Context) ([]docker.ProxyTarget, error) {
+	targets, _ := static.Load(p.path)
+	return targets, nil
+}
+
+func (p *StaticProvider) Health() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.err
+}
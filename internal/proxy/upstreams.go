@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"context"
+
+	"devproxy/internal/caddy"
+	"devproxy/internal/docker"
+)
+
+// Upstream states reported by JoinUpstreams
+const (
+	UpstreamUp      = "up"      // reachable, no recent failures
+	UpstreamFailing = "failing" // Caddy recently failed to reach it
+	UpstreamUnknown = "unknown" // not known to Caddy yet
+)
+
+// UpstreamState is the live state of a route's upstream as seen by Caddy
+type UpstreamState struct {
+	Domain        string `json:"domain"`
+	Path          string `json:"path,omitempty"`
+	ContainerName string `json:"container"`
+	Address       string `json:"address"`
+	State         string `json:"state"`
+	InFlight      int    `json:"in_flight"`
+	Fails         int    `json:"fails"`
+}
+
+// JoinUpstreams matches proxy targets with Caddy's upstream statuses by dial
+// address, returning one state per target in routing order.
+func JoinUpstreams(targets []docker.ProxyTarget, statuses []caddy.UpstreamStatus) []UpstreamState {
+	byAddress := make(map[string]caddy.UpstreamStatus)
+	for _, status := range statuses {
+		byAddress[status.Address] = status
+	}
+
+	sorted := append([]docker.ProxyTarget(nil), targets...)
+	docker.SortTargets(sorted)
+
+	states := []UpstreamState{}
+	for _, target := range sorted {
+		state := UpstreamState{
+			Domain:        target.Domain,
+			Path:          target.Path,
+			ContainerName: target.ContainerName,
+			Address:       target.Dial(),
+			State:         UpstreamUnknown,
+		}
+
+		// Both addresses of dual-stack targets count
+		for _, dial := range target.Dials() {
+			if status, exists := byAddress[dial]; exists {
+				state.InFlight += status.NumRequests
+				state.Fails += status.Fails
+				state.State = UpstreamUp
+			}
+		}
+		if state.Fails > 0 {
+			state.State = UpstreamFailing
+		}
+
+		states = append(states, state)
+	}
+
+	return states
+}
+
+// GetUpstreamStates joins the given targets with Caddy's upstream statuses
+func (m *Manager) GetUpstreamStates(ctx context.Context, targets []docker.ProxyTarget) ([]UpstreamState, error) {
+	statuses, err := m.caddyClient.GetUpstreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinUpstreams(targets, statuses), nil
+}
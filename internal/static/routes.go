@@ -0,0 +1,194 @@
+package static
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"devproxy/internal/docker"
+)
+
+// Source tags targets that come from the static routes file
+const Source = "static"
+
+// File is the static routes file, routing domains to upstreams outside
+// Docker such as dev servers running on the host
+type File struct {
+	Routes []Route `json:"routes"`
+}
+
+// Route maps a domain, and optionally a path, to an upstream given as
+// "host:port" or as a URL whose scheme selects the upstream protocol
+type Route struct {
+	Domain             string  `json:"domain"`
+	Path               string  `json:"path,omitempty"`
+	Upstream           string  `json:"upstream"`
+	TLS                string  `json:"tls,omitempty"`
+	Priority           int     `json:"priority,omitempty"`
+	InsecureSkipVerify bool    `json:"insecure_skip_verify,omitempty"`
+	Headers            Headers `json:"headers,omitempty"`
+}
+
+type Headers struct {
+	Request  HeaderOps `json:"request,omitempty"`
+	Response HeaderOps `json:"response,omitempty"`
+}
+
+type HeaderOps struct {
+	Set    map[string]string `json:"set,omitempty"`
+	Add    map[string]string `json:"add,omitempty"`
+	Delete []string          `json:"delete,omitempty"`
+}
+
+// Load reads the routes file at path. Invalid routes are skipped and
+// reported in the returned error alongside the valid targets.
+func Load(path string) ([]docker.ProxyTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var targets []docker.ProxyTarget
+	var errs []error
+	for i, route := range file.Routes {
+		target, err := route.Target()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %d (%s): %w", i+1, route.Domain, err))
+			continue
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, errors.Join(errs...)
+}
+
+// Target converts the route into a proxy target
+func (r Route) Target() (docker.ProxyTarget, error) {
+	if r.Domain == "" {
+		return docker.ProxyTarget{}, errors.New("missing domain")
+	}
+
+	scheme, host, port, err := parseUpstream(r.Upstream)
+	if err != nil {
+		return docker.ProxyTarget{}, err
+	}
+
+	target := docker.ProxyTarget{
+		Domain:          strings.ToLower(r.Domain),
+		Path:            normalizePath(r.Path),
+		ContainerName:   Source,
+		ContainerIP:     host,
+		Port:            port,
+		Priority:        r.Priority,
+		Scheme:          scheme,
+		RequestHeaders:  r.Headers.Request.headerOps(),
+		ResponseHeaders: r.Headers.Response.headerOps(),
+		Source:          Source,
+	}
+
+	switch r.TLS {
+	case "", docker.TLSModeBoth:
+		target.TLSMode = docker.TLSModeBoth
+	case docker.TLSModeRedirect, docker.TLSModeHTTPSOnly, docker.TLSModeHTTPOnly:
+		target.TLSMode = r.TLS
+	default:
+		return docker.ProxyTarget{}, fmt.Errorf("unknown tls mode %q", r.TLS)
+	}
+
+	if scheme == docker.SchemeHTTPS {
+		target.UpstreamTLS = &docker.UpstreamTLS{
+			InsecureSkipVerify: r.InsecureSkipVerify,
+		}
+		// IP addresses cannot be sent as server names
+		if net.ParseIP(host) == nil {
+			target.UpstreamTLS.ServerName = host
+		}
+	}
+
+	return target, nil
+}
+
+// parseUpstream accepts "host:port" for plain HTTP, or an http, https or
+// h2c URL whose port defaults to the scheme's
+func parseUpstream(upstream string) (scheme, host string, port int, err error) {
+	if upstream == "" {
+		return "", "", 0, errors.New("missing upstream")
+	}
+
+	scheme = docker.SchemeHTTP
+	hostport := upstream
+	if strings.Contains(upstream, "://") {
+		u, err := url.Parse(upstream)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid upstream %q: %w", upstream, err)
+		}
+		switch u.Scheme {
+		case docker.SchemeHTTP, docker.SchemeHTTPS, docker.SchemeH2C:
+			scheme = u.Scheme
+		default:
+			return "", "", 0, fmt.Errorf("unsupported upstream scheme %q", u.Scheme)
+		}
+		if u.Port() == "" {
+			defaultPort := "80"
+			if scheme == docker.SchemeHTTPS {
+				defaultPort = "443"
+			}
+			hostport = net.JoinHostPort(u.Hostname(), defaultPort)
+		} else {
+			hostport = u.Host
+		}
+	}
+
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid upstream %q: %w", upstream, err)
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", "", 0, fmt.Errorf("invalid upstream port %q", portStr)
+	}
+	if host == "" {
+		return "", "", 0, fmt.Errorf("invalid upstream %q: missing host", upstream)
+	}
+
+	return scheme, host, port, nil
+}
+
+func normalizePath(path string) string {
+	path = strings.TrimRight(path, "/")
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+func (ops HeaderOps) headerOps() docker.HeaderOps {
+	var result docker.HeaderOps
+	for name, value := range ops.Set {
+		if result.Set == nil {
+			result.Set = make(map[string]string)
+		}
+		result.Set[http.CanonicalHeaderKey(name)] = value
+	}
+	for name, value := range ops.Add {
+		if result.Add == nil {
+			result.Add = make(map[string]string)
+		}
+		result.Add[http.CanonicalHeaderKey(name)] = value
+	}
+	for _, name := range ops.Delete {
+		result.Delete = append(result.Delete, http.CanonicalHeaderKey(name))
+	}
+	return result
+}